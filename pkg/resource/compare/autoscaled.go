@@ -0,0 +1,41 @@
+package compare
+
+import (
+	"github.com/RHsyseng/operator-utils/pkg/resource"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// AutoscaledTarget identifies a workload scaled by a HorizontalPodAutoscaler, by the group, version and kind of its scale target reference
+type AutoscaledTarget struct {
+	GroupVersionKind schema.GroupVersionKind
+	Namespace        string
+	Name             string
+}
+
+// AutoscaledTargets is the set of workloads whose replica count is owned by an autoscaler
+type AutoscaledTargets map[AutoscaledTarget]bool
+
+// GetAutoscaledTargets returns the scale targets of the given HorizontalPodAutoscalers, other resources and invalid api versions are skipped
+func GetAutoscaledTargets(autoscalers []resource.KubernetesResource) AutoscaledTargets {
+	targets := make(AutoscaledTargets)
+	for _, res := range autoscalers {
+		hpa, ok := res.(*autoscalingv1.HorizontalPodAutoscaler)
+		if !ok {
+			continue
+		}
+		reference := hpa.Spec.ScaleTargetRef
+		groupVersion, err := schema.ParseGroupVersion(reference.APIVersion)
+		if err != nil {
+			logger.Info("Skipping autoscaler with invalid scale target api version", "name", hpa.Name, "apiVersion", reference.APIVersion)
+			continue
+		}
+		targets[AutoscaledTarget{groupVersion.WithKind(reference.Kind), hpa.Namespace, reference.Name}] = true
+	}
+	return targets
+}
+
+// Contains returns true if the object, of the given group, version and kind, is the scale target of an autoscaler
+func (this AutoscaledTargets) Contains(gvk schema.GroupVersionKind, object resource.KubernetesResource) bool {
+	return this[AutoscaledTarget{gvk, object.GetNamespace(), object.GetName()}]
+}
@@ -5,11 +5,20 @@ import (
 	"github.com/RHsyseng/operator-utils/pkg/resource"
 	oappsv1 "github.com/openshift/api/apps/v1"
 	buildv1 "github.com/openshift/api/build/v1"
+	imagev1 "github.com/openshift/api/image/v1"
 	routev1 "github.com/openshift/api/route/v1"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"reflect"
 	"sort"
 	"strings"
@@ -19,15 +28,25 @@ const (
 	imageTriggersAnnotation           = "image.openshift.io/triggers"
 	deploymentRevisionAnnotation      = "deployment.kubernetes.io/revision"
 	imageTriggerContainerNameValueFmt = "spec.template.spec.containers[?(@.name==\\\"%s\\\")].image"
+	jobControllerUIDLabel             = "controller-uid"
+	jobNameLabel                      = "job-name"
+	ingressClassAnnotation            = "kubernetes.io/ingress.class"
+	imageRepositoryCheckAnnotation    = "openshift.io/image.dockerRepositoryCheck"
 )
 
 type resourceComparator struct {
 	defaultCompareFunc func(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool
+	defaultDiffFunc    func(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference
 	compareFuncMap     map[reflect.Type]func(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool
+	diffFuncMap        map[reflect.Type]func(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference
+	keyFunc            func(object resource.KubernetesResource) string
+	autoscaledTargets  AutoscaledTargets
 }
 
 func (this *resourceComparator) SetDefaultComparator(compFunc func(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool) {
 	this.defaultCompareFunc = compFunc
+	//A custom default comparator takes precedence over the built-in default diff logic
+	this.defaultDiffFunc = nil
 }
 
 func (this *resourceComparator) GetDefaultComparator() func(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
@@ -36,13 +55,38 @@ func (this *resourceComparator) GetDefaultComparator() func(deployed resource.Ku
 
 func (this *resourceComparator) SetComparator(resourceType reflect.Type, compFunc func(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool) {
 	this.compareFuncMap[resourceType] = compFunc
+	//A custom comparator takes precedence over any built-in diff logic for the same type
+	delete(this.diffFuncMap, resourceType)
 }
 
 func (this *resourceComparator) GetComparator(resourceType reflect.Type) func(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
 	return this.compareFuncMap[resourceType]
 }
 
+// SetKeyFunc sets the function used to match deployed and requested objects, or restores NamespacedKey if nil
+func (this *resourceComparator) SetKeyFunc(keyFunc func(object resource.KubernetesResource) string) {
+	if keyFunc == nil {
+		keyFunc = NamespacedKey
+	}
+	this.keyFunc = keyFunc
+}
+
+func (this *resourceComparator) GetKeyFunc() func(object resource.KubernetesResource) string {
+	return this.keyFunc
+}
+
+// SetAutoscaledTargets makes the Deployment, DeploymentConfig and StatefulSet comparators ignore the replica count of the given workloads
+// custom comparators set for these types are not affected
+func (this *resourceComparator) SetAutoscaledTargets(targets AutoscaledTargets) {
+	this.autoscaledTargets = targets
+}
+
+func (this *resourceComparator) GetAutoscaledTargets() AutoscaledTargets {
+	return this.autoscaledTargets
+}
+
 func (this *resourceComparator) Compare(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	deployed = withoutLastApplied(deployed)
 	compareFunc := this.GetDefaultComparator()
 	type1 := reflect.ValueOf(deployed).Elem().Type()
 	type2 := reflect.ValueOf(requested).Elem().Type()
@@ -54,22 +98,45 @@ func (this *resourceComparator) Compare(deployed resource.KubernetesResource, re
 	return compareFunc(deployed, requested)
 }
 
+func (this *resourceComparator) CompareWithDiff(deployed resource.KubernetesResource, requested resource.KubernetesResource) (bool, []Difference) {
+	deployed = withoutLastApplied(deployed)
+	type1 := reflect.ValueOf(deployed).Elem().Type()
+	type2 := reflect.ValueOf(requested).Elem().Type()
+	if type1 == type2 {
+		if diffFunc, exists := this.diffFuncMap[type1]; exists {
+			diffs := diffFunc(deployed, requested)
+			return len(diffs) == 0, diffs
+		}
+		if _, exists := this.compareFuncMap[type1]; !exists && this.defaultDiffFunc != nil {
+			diffs := this.defaultDiffFunc(deployed, requested)
+			return len(diffs) == 0, diffs
+		}
+	}
+	if this.Compare(deployed, requested) {
+		return true, nil
+	}
+	//No type-aware diff is available, so report every difference between the two objects
+	return false, Diff(deployed, requested)
+}
+
 func (this *resourceComparator) CompareArrays(deployed []resource.KubernetesResource, requested []resource.KubernetesResource) ResourceDelta {
-	deployedMap := getObjectMap(deployed)
-	requestedMap := getObjectMap(requested)
+	deployedMap := getObjectMap(deployed, this.GetKeyFunc())
+	requestedMap := getObjectMap(requested, this.GetKeyFunc())
 	var added []resource.KubernetesResource
 	var updated []resource.KubernetesResource
 	var removed []resource.KubernetesResource
-	for name, requestedObject := range requestedMap {
-		deployedObject := deployedMap[name]
+	diffs := make(map[resource.KubernetesResource][]Difference)
+	for key, requestedObject := range requestedMap {
+		deployedObject := deployedMap[key]
 		if deployedObject == nil {
 			added = append(added, requestedObject)
-		} else if !this.Compare(deployedObject, requestedObject) {
+		} else if equal, differences := this.CompareWithDiff(deployedObject, requestedObject); !equal {
 			updated = append(updated, requestedObject)
+			diffs[requestedObject] = differences
 		}
 	}
-	for name, deployedObject := range deployedMap {
-		if requestedMap[name] == nil {
+	for key, deployedObject := range deployedMap {
+		if requestedMap[key] == nil {
 			removed = append(removed, deployedObject)
 		}
 	}
@@ -77,32 +144,116 @@ func (this *resourceComparator) CompareArrays(deployed []resource.KubernetesReso
 		Added:   added,
 		Updated: updated,
 		Removed: removed,
+		Diffs:   diffs,
 	}
 }
 
-func getObjectMap(objects []resource.KubernetesResource) map[string]resource.KubernetesResource {
+// withoutLastApplied returns a copy of the deployed object without the annotation set by the writer in patch mode, which is never requested
+func withoutLastApplied(deployed resource.KubernetesResource) resource.KubernetesResource {
+	if _, found := deployed.GetAnnotations()[resource.LastAppliedAnnotation]; !found {
+		return deployed
+	}
+	deployedCopy := deployed.DeepCopyObject().(resource.KubernetesResource)
+	annotations := deployedCopy.GetAnnotations()
+	delete(annotations, resource.LastAppliedAnnotation)
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	deployedCopy.SetAnnotations(annotations)
+	return deployedCopy
+}
+
+func getObjectMap(objects []resource.KubernetesResource, keyFunc func(object resource.KubernetesResource) string) map[string]resource.KubernetesResource {
 	objectMap := make(map[string]resource.KubernetesResource)
 	for index := range objects {
-		objectMap[objects[index].GetName()] = objects[index]
+		objectMap[keyFunc(objects[index])] = objects[index]
 	}
 	return objectMap
 }
 
-func defaultMap() map[reflect.Type]func(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+// NamespacedKey identifies an object by its namespace and name, or just its name for cluster-scoped objects
+func NamespacedKey(object resource.KubernetesResource) string {
+	if object.GetNamespace() == "" {
+		return object.GetName()
+	}
+	return object.GetNamespace() + "/" + object.GetName()
+}
+
+func defaultMap(autoscaled *AutoscaledTargets) map[reflect.Type]func(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
 	equalsMap := make(map[reflect.Type]func(resource.KubernetesResource, resource.KubernetesResource) bool)
-	equalsMap[reflect.TypeOf(oappsv1.DeploymentConfig{})] = equalDeploymentConfigs
-	equalsMap[reflect.TypeOf(appsv1.Deployment{})] = equalDeployment
+	equalsMap[reflect.TypeOf(oappsv1.DeploymentConfig{})] = func(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+		return equalDeploymentConfigs(deployed, requested, *autoscaled)
+	}
+	equalsMap[reflect.TypeOf(appsv1.Deployment{})] = func(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+		return equalDeployment(deployed, requested, *autoscaled)
+	}
+	equalsMap[reflect.TypeOf(appsv1.StatefulSet{})] = func(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+		return equalStatefulSets(deployed, requested, *autoscaled)
+	}
+	equalsMap[reflect.TypeOf(appsv1.DaemonSet{})] = equalDaemonSets
+	equalsMap[reflect.TypeOf(batchv1.Job{})] = equalJobs
+	equalsMap[reflect.TypeOf(batchv1beta1.CronJob{})] = equalCronJobs
+	equalsMap[reflect.TypeOf(autoscalingv1.HorizontalPodAutoscaler{})] = equalHorizontalPodAutoscalers
+	equalsMap[reflect.TypeOf(policyv1beta1.PodDisruptionBudget{})] = equalPodDisruptionBudgets
 	equalsMap[reflect.TypeOf(corev1.Service{})] = equalServices
 	equalsMap[reflect.TypeOf(routev1.Route{})] = equalRoutes
+	equalsMap[reflect.TypeOf(extensionsv1beta1.Ingress{})] = equalIngresses
+	equalsMap[reflect.TypeOf(networkingv1.NetworkPolicy{})] = equalNetworkPolicies
 	equalsMap[reflect.TypeOf(rbacv1.Role{})] = equalRoles
 	equalsMap[reflect.TypeOf(rbacv1.RoleBinding{})] = equalRoleBindings
+	equalsMap[reflect.TypeOf(rbacv1.ClusterRole{})] = equalClusterRoles
+	equalsMap[reflect.TypeOf(rbacv1.ClusterRoleBinding{})] = equalClusterRoleBindings
 	equalsMap[reflect.TypeOf(corev1.ServiceAccount{})] = equalServiceAccounts
 	equalsMap[reflect.TypeOf(corev1.Secret{})] = equalSecrets
+	equalsMap[reflect.TypeOf(corev1.ConfigMap{})] = equalConfigMaps
+	equalsMap[reflect.TypeOf(corev1.PersistentVolumeClaim{})] = equalPersistentVolumeClaims
 	equalsMap[reflect.TypeOf(buildv1.BuildConfig{})] = equalBuildConfigs
+	equalsMap[reflect.TypeOf(imagev1.ImageStream{})] = equalImageStreams
+	equalsMap[reflect.TypeOf(apiextensionsv1beta1.CustomResourceDefinition{})] = equalCustomResourceDefinitions
+	equalsMap[reflect.TypeOf(unstructured.Unstructured{})] = equalUnstructured
 	return equalsMap
 }
 
-func equalDeploymentConfigs(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+func defaultDiffMap(autoscaled *AutoscaledTargets) map[reflect.Type]func(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
+	diffMap := make(map[reflect.Type]func(resource.KubernetesResource, resource.KubernetesResource) []Difference)
+	diffMap[reflect.TypeOf(oappsv1.DeploymentConfig{})] = func(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
+		return diffDeploymentConfigs(deployed, requested, *autoscaled)
+	}
+	diffMap[reflect.TypeOf(appsv1.Deployment{})] = func(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
+		return diffDeployment(deployed, requested, *autoscaled)
+	}
+	diffMap[reflect.TypeOf(appsv1.StatefulSet{})] = func(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
+		return diffStatefulSets(deployed, requested, *autoscaled)
+	}
+	diffMap[reflect.TypeOf(appsv1.DaemonSet{})] = diffDaemonSets
+	diffMap[reflect.TypeOf(batchv1.Job{})] = diffJobs
+	diffMap[reflect.TypeOf(batchv1beta1.CronJob{})] = diffCronJobs
+	diffMap[reflect.TypeOf(autoscalingv1.HorizontalPodAutoscaler{})] = diffHorizontalPodAutoscalers
+	diffMap[reflect.TypeOf(policyv1beta1.PodDisruptionBudget{})] = diffPodDisruptionBudgets
+	diffMap[reflect.TypeOf(corev1.Service{})] = diffServices
+	diffMap[reflect.TypeOf(routev1.Route{})] = diffRoutes
+	diffMap[reflect.TypeOf(extensionsv1beta1.Ingress{})] = diffIngresses
+	diffMap[reflect.TypeOf(networkingv1.NetworkPolicy{})] = diffNetworkPolicies
+	diffMap[reflect.TypeOf(rbacv1.Role{})] = diffRoles
+	diffMap[reflect.TypeOf(rbacv1.RoleBinding{})] = diffRoleBindings
+	diffMap[reflect.TypeOf(rbacv1.ClusterRole{})] = diffClusterRoles
+	diffMap[reflect.TypeOf(rbacv1.ClusterRoleBinding{})] = diffClusterRoleBindings
+	diffMap[reflect.TypeOf(corev1.ServiceAccount{})] = diffServiceAccounts
+	diffMap[reflect.TypeOf(corev1.Secret{})] = diffSecrets
+	diffMap[reflect.TypeOf(corev1.ConfigMap{})] = diffConfigMaps
+	diffMap[reflect.TypeOf(corev1.PersistentVolumeClaim{})] = diffPersistentVolumeClaims
+	diffMap[reflect.TypeOf(buildv1.BuildConfig{})] = diffBuildConfigs
+	diffMap[reflect.TypeOf(imagev1.ImageStream{})] = diffImageStreams
+	diffMap[reflect.TypeOf(apiextensionsv1beta1.CustomResourceDefinition{})] = diffCustomResourceDefinitions
+	diffMap[reflect.TypeOf(unstructured.Unstructured{})] = diffUnstructured
+	return diffMap
+}
+
+func equalDeploymentConfigs(deployed resource.KubernetesResource, requested resource.KubernetesResource, autoscaled AutoscaledTargets) bool {
+	return len(diffDeploymentConfigs(deployed, requested, autoscaled)) == 0
+}
+
+func diffDeploymentConfigs(deployed resource.KubernetesResource, requested resource.KubernetesResource, autoscaled AutoscaledTargets) []Difference {
 	dc1 := deployed.(*oappsv1.DeploymentConfig)
 	dc2 := requested.(*oappsv1.DeploymentConfig)
 
@@ -140,6 +291,10 @@ func equalDeploymentConfigs(deployed resource.KubernetesResource, requested reso
 	if dc2.Spec.RevisionHistoryLimit == nil {
 		dc1.Spec.RevisionHistoryLimit = nil
 	}
+	if autoscaled.Contains(oappsv1.SchemeGroupVersion.WithKind("DeploymentConfig"), dc2) {
+		//The replica count is owned by an autoscaler
+		dc1.Spec.Replicas = dc2.Spec.Replicas
+	}
 	if len(dc1.Spec.Triggers) == 1 && len(dc2.Spec.Triggers) == 0 {
 		defaultTrigger := oappsv1.DeploymentTriggerPolicy{Type: oappsv1.DeploymentTriggerOnConfigChange}
 		if dc1.Spec.Triggers[0] == defaultTrigger {
@@ -150,7 +305,7 @@ func equalDeploymentConfigs(deployed resource.KubernetesResource, requested reso
 	for i := range dc1.Spec.Triggers {
 		if len(dc2.Spec.Triggers) <= i {
 			logger.Info("No matching trigger found in requested DC", "deployed.DC.trigger", dc1.Spec.Triggers[i])
-			return false
+			return []Difference{{Path: "spec.triggers", Deployed: dc1.Spec.Triggers, Requested: dc2.Spec.Triggers}}
 		}
 		if dc1.Spec.Triggers[i].ImageChangeParams != nil && dc2.Spec.Triggers[i].ImageChangeParams != nil {
 			if dc2.Spec.Triggers[i].ImageChangeParams.LastTriggeredImage == "" {
@@ -162,30 +317,30 @@ func equalDeploymentConfigs(deployed resource.KubernetesResource, requested reso
 		}
 	}
 	if !checkGeneratePodValues(dc1.Spec.Template, dc2.Spec.Template, triggerBasedImage) {
-		return false
+		return []Difference{{Path: "spec.template.spec.volumes", Deployed: dc1.Spec.Template.Spec.Volumes, Requested: dc2.Spec.Template.Spec.Volumes}}
 	}
 	ignoreEmptyMaps(dc1, dc2)
 	sortDeploymentVars(dc1.Spec.Template, dc2.Spec.Template)
 
-	var pairs [][2]interface{}
-	pairs = append(pairs, [2]interface{}{dc1.Name, dc2.Name})
-	pairs = append(pairs, [2]interface{}{dc1.Namespace, dc2.Namespace})
-	pairs = append(pairs, [2]interface{}{dc1.Labels, dc2.Labels})
-	pairs = append(pairs, [2]interface{}{dc1.Annotations, dc2.Annotations})
-	pairs = append(pairs, [2]interface{}{dc1.Spec, dc2.Spec})
-	equal := EqualPairs(pairs)
-	if !equal {
-		logger.Info("Resources are not equal", "deployed", deployed, "requested", requested)
-	}
-	return equal
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", dc1.Name, dc2.Name})
+	pairs = append(pairs, fieldPair{"metadata.namespace", dc1.Namespace, dc2.Namespace})
+	pairs = append(pairs, fieldPair{"metadata.labels", dc1.Labels, dc2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", dc1.Annotations, dc2.Annotations})
+	pairs = append(pairs, fieldPair{"spec", dc1.Spec, dc2.Spec})
+	return diffResources(deployed, pairs)
+}
+
+func equalDeployment(deployed resource.KubernetesResource, requested resource.KubernetesResource, autoscaled AutoscaledTargets) bool {
+	return len(diffDeployment(deployed, requested, autoscaled)) == 0
 }
 
-func equalDeployment(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+func diffDeployment(deployed resource.KubernetesResource, requested resource.KubernetesResource, autoscaled AutoscaledTargets) []Difference {
 	d1 := deployed.(*appsv1.Deployment)
 	d2 := requested.(*appsv1.Deployment)
 
 	d1 = d1.DeepCopy()
-	triggerBasedImage := make(map[string]bool)
+	var triggerBasedImage map[string]bool
 
 	if d2.Spec.Strategy.RollingUpdate == nil && d1.Spec.Strategy.RollingUpdate != nil {
 		d1.Spec.Strategy.RollingUpdate = nil
@@ -204,22 +359,15 @@ func equalDeployment(deployed resource.KubernetesResource, requested resource.Ku
 	if d2.Spec.ProgressDeadlineSeconds == nil {
 		d1.Spec.ProgressDeadlineSeconds = nil
 	}
+	if autoscaled.Contains(appsv1.SchemeGroupVersion.WithKind("Deployment"), d2) {
+		//The replica count is owned by an autoscaler
+		d1.Spec.Replicas = d2.Spec.Replicas
+	}
 
 	if &d1.Spec.Template != nil && &d2.Spec.Template != nil {
-		if v, ok := d1.Annotations[imageTriggersAnnotation]; ok {
-			for _, container := range d1.Spec.Template.Spec.Containers {
-				if strings.Contains(v, fmt.Sprintf(imageTriggerContainerNameValueFmt, container.Name)) {
-					triggerBasedImage[container.Name] = true
-				}
-			}
-			for _, initContainer := range d1.Spec.Template.Spec.InitContainers {
-				if strings.Contains(v, fmt.Sprintf(imageTriggerContainerNameValueFmt, initContainer.Name)) {
-					triggerBasedImage[initContainer.Name] = true
-				}
-			}
-		}
+		triggerBasedImage = getTriggerBasedImages(d1.Annotations, &d1.Spec.Template)
 		if !checkGeneratePodValues(&d1.Spec.Template, &d2.Spec.Template, triggerBasedImage) {
-			return false
+			return []Difference{{Path: "spec.template.spec.volumes", Deployed: d1.Spec.Template.Spec.Volumes, Requested: d2.Spec.Template.Spec.Volumes}}
 		}
 	}
 
@@ -228,17 +376,287 @@ func equalDeployment(deployed resource.KubernetesResource, requested resource.Ku
 	ignoreEmptyMaps(d1, d2)
 	sortDeploymentVars(&d1.Spec.Template, &d2.Spec.Template)
 
-	var pairs [][2]interface{}
-	pairs = append(pairs, [2]interface{}{d1.Name, d2.Name})
-	pairs = append(pairs, [2]interface{}{d1.Namespace, d2.Namespace})
-	pairs = append(pairs, [2]interface{}{d1.Labels, d2.Labels})
-	pairs = append(pairs, [2]interface{}{d1.Annotations, d2.Annotations})
-	pairs = append(pairs, [2]interface{}{d1.Spec, d2.Spec})
-	equal := EqualPairs(pairs)
-	if !equal {
-		logger.Info("Resources are not equal", "deployed", deployed, "requested", requested)
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", d1.Name, d2.Name})
+	pairs = append(pairs, fieldPair{"metadata.namespace", d1.Namespace, d2.Namespace})
+	pairs = append(pairs, fieldPair{"metadata.labels", d1.Labels, d2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", d1.Annotations, d2.Annotations})
+	pairs = append(pairs, fieldPair{"spec", d1.Spec, d2.Spec})
+	return diffResources(deployed, pairs)
+}
+
+func getTriggerBasedImages(annotations map[string]string, template *corev1.PodTemplateSpec) map[string]bool {
+	triggerBasedImage := make(map[string]bool)
+	if v, ok := annotations[imageTriggersAnnotation]; ok {
+		for _, container := range template.Spec.Containers {
+			if strings.Contains(v, fmt.Sprintf(imageTriggerContainerNameValueFmt, container.Name)) {
+				triggerBasedImage[container.Name] = true
+			}
+		}
+		for _, initContainer := range template.Spec.InitContainers {
+			if strings.Contains(v, fmt.Sprintf(imageTriggerContainerNameValueFmt, initContainer.Name)) {
+				triggerBasedImage[initContainer.Name] = true
+			}
+		}
 	}
-	return equal
+	return triggerBasedImage
+}
+
+func equalStatefulSets(deployed resource.KubernetesResource, requested resource.KubernetesResource, autoscaled AutoscaledTargets) bool {
+	return len(diffStatefulSets(deployed, requested, autoscaled)) == 0
+}
+
+func diffStatefulSets(deployed resource.KubernetesResource, requested resource.KubernetesResource, autoscaled AutoscaledTargets) []Difference {
+	ss1 := deployed.(*appsv1.StatefulSet)
+	ss2 := requested.(*appsv1.StatefulSet)
+
+	ss1 = ss1.DeepCopy()
+	ss2 = ss2.DeepCopy()
+
+	//Removed generated fields from deployed version, when not specified in requested item
+	if ss2.Spec.Replicas == nil || autoscaled.Contains(appsv1.SchemeGroupVersion.WithKind("StatefulSet"), ss2) {
+		//Either generated or owned by an autoscaler
+		ss1.Spec.Replicas = ss2.Spec.Replicas
+	}
+	if ss2.Spec.PodManagementPolicy == "" {
+		ss1.Spec.PodManagementPolicy = ""
+	}
+	if ss2.Spec.UpdateStrategy.Type == "" {
+		ss1.Spec.UpdateStrategy.Type = ""
+	}
+	if ss2.Spec.UpdateStrategy.RollingUpdate == nil {
+		ss1.Spec.UpdateStrategy.RollingUpdate = nil
+	}
+	if ss2.Spec.RevisionHistoryLimit == nil {
+		ss1.Spec.RevisionHistoryLimit = nil
+	}
+	for i := range ss1.Spec.VolumeClaimTemplates {
+		if len(ss2.Spec.VolumeClaimTemplates) <= i {
+			break
+		}
+		claim1 := &ss1.Spec.VolumeClaimTemplates[i]
+		claim2 := &ss2.Spec.VolumeClaimTemplates[i]
+		claim1.Status = claim2.Status
+		if claim2.Spec.VolumeMode == nil {
+			claim1.Spec.VolumeMode = nil
+		}
+		if claim2.Spec.StorageClassName == nil {
+			claim1.Spec.StorageClassName = nil
+		}
+	}
+
+	triggerBasedImage := getTriggerBasedImages(ss1.Annotations, &ss1.Spec.Template)
+	if !checkGeneratePodValues(&ss1.Spec.Template, &ss2.Spec.Template, triggerBasedImage) {
+		return []Difference{{Path: "spec.template.spec.volumes", Deployed: ss1.Spec.Template.Spec.Volumes, Requested: ss2.Spec.Template.Spec.Volumes}}
+	}
+
+	ignoreEmptyMaps(ss1, ss2)
+	sortDeploymentVars(&ss1.Spec.Template, &ss2.Spec.Template)
+
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", ss1.Name, ss2.Name})
+	pairs = append(pairs, fieldPair{"metadata.namespace", ss1.Namespace, ss2.Namespace})
+	pairs = append(pairs, fieldPair{"metadata.labels", ss1.Labels, ss2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", ss1.Annotations, ss2.Annotations})
+	pairs = append(pairs, fieldPair{"spec", ss1.Spec, ss2.Spec})
+	return diffResources(deployed, pairs)
+}
+
+func equalDaemonSets(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	return len(diffDaemonSets(deployed, requested)) == 0
+}
+
+func diffDaemonSets(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
+	ds1 := deployed.(*appsv1.DaemonSet)
+	ds2 := requested.(*appsv1.DaemonSet)
+
+	ds1 = ds1.DeepCopy()
+	ds2 = ds2.DeepCopy()
+
+	//Removed generated fields from deployed version, when not specified in requested item
+	if ds2.Spec.UpdateStrategy.Type == "" {
+		ds1.Spec.UpdateStrategy.Type = ""
+	}
+	if ds2.Spec.UpdateStrategy.RollingUpdate == nil {
+		ds1.Spec.UpdateStrategy.RollingUpdate = nil
+	}
+	if ds2.Spec.RevisionHistoryLimit == nil {
+		ds1.Spec.RevisionHistoryLimit = nil
+	}
+
+	triggerBasedImage := getTriggerBasedImages(ds1.Annotations, &ds1.Spec.Template)
+	if !checkGeneratePodValues(&ds1.Spec.Template, &ds2.Spec.Template, triggerBasedImage) {
+		return []Difference{{Path: "spec.template.spec.volumes", Deployed: ds1.Spec.Template.Spec.Volumes, Requested: ds2.Spec.Template.Spec.Volumes}}
+	}
+
+	ignoreEmptyMaps(ds1, ds2)
+	sortDeploymentVars(&ds1.Spec.Template, &ds2.Spec.Template)
+
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", ds1.Name, ds2.Name})
+	pairs = append(pairs, fieldPair{"metadata.namespace", ds1.Namespace, ds2.Namespace})
+	pairs = append(pairs, fieldPair{"metadata.labels", ds1.Labels, ds2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", ds1.Annotations, ds2.Annotations})
+	pairs = append(pairs, fieldPair{"spec", ds1.Spec, ds2.Spec})
+	return diffResources(deployed, pairs)
+}
+
+func equalJobs(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	return len(diffJobs(deployed, requested)) == 0
+}
+
+func diffJobs(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
+	job1 := deployed.(*batchv1.Job)
+	job2 := requested.(*batchv1.Job)
+
+	job1 = job1.DeepCopy()
+	job2 = job2.DeepCopy()
+
+	//The job controller labels the job itself, as well as its pod template
+	for _, label := range []string{jobControllerUIDLabel, jobNameLabel} {
+		if _, ok := job2.Labels[label]; !ok {
+			delete(job1.Labels, label)
+		}
+	}
+	if !ignoreGeneratedJobValues(&job1.Spec, &job2.Spec) {
+		return []Difference{{Path: "spec.template.spec.volumes", Deployed: job1.Spec.Template.Spec.Volumes, Requested: job2.Spec.Template.Spec.Volumes}}
+	}
+
+	ignoreEmptyMaps(job1, job2)
+
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", job1.Name, job2.Name})
+	pairs = append(pairs, fieldPair{"metadata.namespace", job1.Namespace, job2.Namespace})
+	pairs = append(pairs, fieldPair{"metadata.labels", job1.Labels, job2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", job1.Annotations, job2.Annotations})
+	pairs = append(pairs, fieldPair{"spec", job1.Spec, job2.Spec})
+	return diffResources(deployed, pairs)
+}
+
+func equalCronJobs(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	return len(diffCronJobs(deployed, requested)) == 0
+}
+
+func diffCronJobs(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
+	cj1 := deployed.(*batchv1beta1.CronJob)
+	cj2 := requested.(*batchv1beta1.CronJob)
+
+	cj1 = cj1.DeepCopy()
+	cj2 = cj2.DeepCopy()
+
+	//Removed generated fields from deployed version, when not specified in requested item
+	if cj2.Spec.ConcurrencyPolicy == "" {
+		cj1.Spec.ConcurrencyPolicy = ""
+	}
+	if cj2.Spec.Suspend == nil {
+		cj1.Spec.Suspend = nil
+	}
+	if cj2.Spec.SuccessfulJobsHistoryLimit == nil {
+		cj1.Spec.SuccessfulJobsHistoryLimit = nil
+	}
+	if cj2.Spec.FailedJobsHistoryLimit == nil {
+		cj1.Spec.FailedJobsHistoryLimit = nil
+	}
+	if !ignoreGeneratedJobValues(&cj1.Spec.JobTemplate.Spec, &cj2.Spec.JobTemplate.Spec) {
+		return []Difference{{Path: "spec.jobTemplate.spec.template.spec.volumes", Deployed: cj1.Spec.JobTemplate.Spec.Template.Spec.Volumes, Requested: cj2.Spec.JobTemplate.Spec.Template.Spec.Volumes}}
+	}
+
+	ignoreEmptyMaps(cj1, cj2)
+
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", cj1.Name, cj2.Name})
+	pairs = append(pairs, fieldPair{"metadata.namespace", cj1.Namespace, cj2.Namespace})
+	pairs = append(pairs, fieldPair{"metadata.labels", cj1.Labels, cj2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", cj1.Annotations, cj2.Annotations})
+	pairs = append(pairs, fieldPair{"spec", cj1.Spec, cj2.Spec})
+	return diffResources(deployed, pairs)
+}
+
+// Annotations used by the autoscaling/v1 API to round-trip status and metrics that only exist in newer versions
+var generatedAutoscalerAnnotations = []string{
+	"autoscaling.alpha.kubernetes.io/conditions",
+	"autoscaling.alpha.kubernetes.io/current-metrics",
+}
+
+func equalHorizontalPodAutoscalers(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	return len(diffHorizontalPodAutoscalers(deployed, requested)) == 0
+}
+
+func diffHorizontalPodAutoscalers(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
+	hpa1 := deployed.(*autoscalingv1.HorizontalPodAutoscaler)
+	hpa2 := requested.(*autoscalingv1.HorizontalPodAutoscaler)
+	hpa1 = hpa1.DeepCopy()
+
+	//Removed generated fields from deployed version, that are not specified in requested item
+	for _, annotation := range generatedAutoscalerAnnotations {
+		if _, ok := hpa2.Annotations[annotation]; !ok {
+			delete(hpa1.Annotations, annotation)
+		}
+	}
+	if hpa2.Spec.MinReplicas == nil {
+		hpa1.Spec.MinReplicas = nil
+	}
+
+	ignoreEmptyMaps(hpa1, hpa2)
+
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", hpa1.Name, hpa2.Name})
+	pairs = append(pairs, fieldPair{"metadata.namespace", hpa1.Namespace, hpa2.Namespace})
+	pairs = append(pairs, fieldPair{"metadata.labels", hpa1.Labels, hpa2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", hpa1.Annotations, hpa2.Annotations})
+	pairs = append(pairs, fieldPair{"spec", hpa1.Spec, hpa2.Spec})
+	return diffResources(deployed, pairs)
+}
+
+func equalPodDisruptionBudgets(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	return len(diffPodDisruptionBudgets(deployed, requested)) == 0
+}
+
+func diffPodDisruptionBudgets(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
+	pdb1 := deployed.(*policyv1beta1.PodDisruptionBudget)
+	pdb2 := requested.(*policyv1beta1.PodDisruptionBudget)
+	pdb1 = pdb1.DeepCopy()
+	ignoreEmptyMaps(pdb1, pdb2)
+
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", pdb1.Name, pdb2.Name})
+	pairs = append(pairs, fieldPair{"metadata.namespace", pdb1.Namespace, pdb2.Namespace})
+	pairs = append(pairs, fieldPair{"metadata.labels", pdb1.Labels, pdb2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", pdb1.Annotations, pdb2.Annotations})
+	pairs = append(pairs, fieldPair{"spec", pdb1.Spec, pdb2.Spec})
+	return diffResources(deployed, pairs)
+}
+
+func ignoreGeneratedJobValues(spec1 *batchv1.JobSpec, spec2 *batchv1.JobSpec) bool {
+	if spec2.Parallelism == nil {
+		spec1.Parallelism = nil
+	}
+	if spec2.Completions == nil {
+		spec1.Completions = nil
+	}
+	if spec2.BackoffLimit == nil {
+		spec1.BackoffLimit = nil
+	}
+	if spec2.Selector == nil {
+		//Selector is generated by the server and cannot be changed
+		spec1.Selector = nil
+	}
+	if spec2.ManualSelector == nil {
+		spec1.ManualSelector = nil
+	}
+	for _, label := range []string{jobControllerUIDLabel, jobNameLabel} {
+		if _, ok := spec2.Template.Labels[label]; !ok {
+			delete(spec1.Template.Labels, label)
+		}
+	}
+	if len(spec1.Template.Labels) == 0 && spec2.Template.Labels == nil {
+		spec1.Template.Labels = nil
+	}
+	if !checkGeneratePodValues(&spec1.Template, &spec2.Template, nil) {
+		return false
+	}
+	sortDeploymentVars(&spec1.Template, &spec2.Template)
+	return true
 }
 
 func sortBuildConfigVars(bc1 *buildv1.BuildConfig, bc2 *buildv1.BuildConfig) {
@@ -380,6 +798,10 @@ func ignoreGenerateContainerValues(containers1 []corev1.Container, containers2 [
 }
 
 func equalServices(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	return len(diffServices(deployed, requested)) == 0
+}
+
+func diffServices(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
 	service1 := deployed.(*corev1.Service)
 	service2 := requested.(*corev1.Service)
 
@@ -407,17 +829,13 @@ func equalServices(deployed resource.KubernetesResource, requested resource.Kube
 	}
 	ignoreEmptyMaps(service1, service2)
 
-	var pairs [][2]interface{}
-	pairs = append(pairs, [2]interface{}{service1.Name, service2.Name})
-	pairs = append(pairs, [2]interface{}{service1.Namespace, service2.Namespace})
-	pairs = append(pairs, [2]interface{}{service1.Labels, service2.Labels})
-	pairs = append(pairs, [2]interface{}{service1.Annotations, service2.Annotations})
-	pairs = append(pairs, [2]interface{}{service1.Spec, service2.Spec})
-	equal := EqualPairs(pairs)
-	if !equal {
-		logger.Info("Resources are not equal", "deployed", deployed, "requested", requested)
-	}
-	return equal
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", service1.Name, service2.Name})
+	pairs = append(pairs, fieldPair{"metadata.namespace", service1.Namespace, service2.Namespace})
+	pairs = append(pairs, fieldPair{"metadata.labels", service1.Labels, service2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", service1.Annotations, service2.Annotations})
+	pairs = append(pairs, fieldPair{"spec", service1.Spec, service2.Spec})
+	return diffResources(deployed, pairs)
 }
 
 func findServicePort(port corev1.ServicePort, ports []corev1.ServicePort) (bool, *corev1.ServicePort) {
@@ -430,6 +848,10 @@ func findServicePort(port corev1.ServicePort, ports []corev1.ServicePort) (bool,
 }
 
 func equalRoutes(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	return len(diffRoutes(deployed, requested)) == 0
+}
+
+func diffRoutes(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
 	route1 := deployed.(*routev1.Route)
 	route2 := requested.(*routev1.Route)
 	route1 = route1.DeepCopy()
@@ -453,83 +875,209 @@ func equalRoutes(deployed resource.KubernetesResource, requested resource.Kubern
 	}
 	ignoreEmptyMaps(route1, route2)
 
-	var pairs [][2]interface{}
-	pairs = append(pairs, [2]interface{}{route1.Name, route2.Name})
-	pairs = append(pairs, [2]interface{}{route1.Namespace, route2.Namespace})
-	pairs = append(pairs, [2]interface{}{route1.Labels, route2.Labels})
-	pairs = append(pairs, [2]interface{}{route1.Annotations, route2.Annotations})
-	pairs = append(pairs, [2]interface{}{route1.Spec, route2.Spec})
-	equal := EqualPairs(pairs)
-	if !equal {
-		logger.Info("Resources are not equal", "deployed", deployed, "requested", requested)
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", route1.Name, route2.Name})
+	pairs = append(pairs, fieldPair{"metadata.namespace", route1.Namespace, route2.Namespace})
+	pairs = append(pairs, fieldPair{"metadata.labels", route1.Labels, route2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", route1.Annotations, route2.Annotations})
+	pairs = append(pairs, fieldPair{"spec", route1.Spec, route2.Spec})
+	return diffResources(deployed, pairs)
+}
+
+// Annotations written by ingress controllers to record the load balancer resources they created
+var generatedIngressAnnotations = []string{
+	"ingress.kubernetes.io/backends",
+	"ingress.kubernetes.io/forwarding-rule",
+	"ingress.kubernetes.io/https-forwarding-rule",
+	"ingress.kubernetes.io/https-target-proxy",
+	"ingress.kubernetes.io/ssl-cert",
+	"ingress.kubernetes.io/static-ip",
+	"ingress.kubernetes.io/target-proxy",
+	"ingress.kubernetes.io/url-map",
+}
+
+func equalIngresses(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	return len(diffIngresses(deployed, requested)) == 0
+}
+
+// diffIngresses compares extensions/v1beta1 ingresses, the only version served by K8S 1.13
+// this version has no pathType or ingressClassName fields, so the ingress class is normalized through its annotation instead
+func diffIngresses(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
+	ingress1 := deployed.(*extensionsv1beta1.Ingress)
+	ingress2 := requested.(*extensionsv1beta1.Ingress)
+	ingress1 = ingress1.DeepCopy()
+	ingress2 = ingress2.DeepCopy()
+
+	//Removed generated fields from deployed version, that are not specified in requested item
+	for _, annotation := range generatedIngressAnnotations {
+		if _, ok := ingress2.Annotations[annotation]; !ok {
+			delete(ingress1.Annotations, annotation)
+		}
+	}
+	//An empty ingress class is the same as no ingress class
+	for _, ingress := range []*extensionsv1beta1.Ingress{ingress1, ingress2} {
+		if ingress.Annotations[ingressClassAnnotation] == "" {
+			delete(ingress.Annotations, ingressClassAnnotation)
+		}
+	}
+
+	ignoreEmptyMaps(ingress1, ingress2)
+
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", ingress1.Name, ingress2.Name})
+	pairs = append(pairs, fieldPair{"metadata.namespace", ingress1.Namespace, ingress2.Namespace})
+	pairs = append(pairs, fieldPair{"metadata.labels", ingress1.Labels, ingress2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", ingress1.Annotations, ingress2.Annotations})
+	pairs = append(pairs, fieldPair{"spec", ingress1.Spec, ingress2.Spec})
+	return diffResources(deployed, pairs)
+}
+
+func equalNetworkPolicies(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	return len(diffNetworkPolicies(deployed, requested)) == 0
+}
+
+func diffNetworkPolicies(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
+	policy1 := deployed.(*networkingv1.NetworkPolicy)
+	policy2 := requested.(*networkingv1.NetworkPolicy)
+	policy1 = policy1.DeepCopy()
+
+	//Removed generated fields from deployed version, that are not specified in requested item
+	if len(policy2.Spec.PolicyTypes) == 0 {
+		policy1.Spec.PolicyTypes = policy2.Spec.PolicyTypes
+	}
+	for i := range policy1.Spec.Ingress {
+		if len(policy2.Spec.Ingress) <= i {
+			break
+		}
+		ignoreDefaultProtocols(policy1.Spec.Ingress[i].Ports, policy2.Spec.Ingress[i].Ports)
+	}
+	for i := range policy1.Spec.Egress {
+		if len(policy2.Spec.Egress) <= i {
+			break
+		}
+		ignoreDefaultProtocols(policy1.Spec.Egress[i].Ports, policy2.Spec.Egress[i].Ports)
+	}
+	ignoreEmptyMaps(policy1, policy2)
+
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", policy1.Name, policy2.Name})
+	pairs = append(pairs, fieldPair{"metadata.namespace", policy1.Namespace, policy2.Namespace})
+	pairs = append(pairs, fieldPair{"metadata.labels", policy1.Labels, policy2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", policy1.Annotations, policy2.Annotations})
+	pairs = append(pairs, fieldPair{"spec", policy1.Spec, policy2.Spec})
+	return diffResources(deployed, pairs)
+}
+
+func ignoreDefaultProtocols(ports1 []networkingv1.NetworkPolicyPort, ports2 []networkingv1.NetworkPolicyPort) {
+	for i := range ports1 {
+		if len(ports2) <= i {
+			return
+		}
+		if ports2[i].Protocol == nil && ports1[i].Protocol != nil && *ports1[i].Protocol == corev1.ProtocolTCP {
+			ports1[i].Protocol = nil
+		}
 	}
-	return equal
 }
 
 func equalRoles(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	return len(diffRoles(deployed, requested)) == 0
+}
+
+func diffRoles(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
 	role1 := deployed.(*rbacv1.Role)
 	role2 := requested.(*rbacv1.Role)
-	var pairs [][2]interface{}
-	pairs = append(pairs, [2]interface{}{role1.Name, role2.Name})
-	pairs = append(pairs, [2]interface{}{role1.Namespace, role2.Namespace})
-	pairs = append(pairs, [2]interface{}{role1.Labels, role2.Labels})
-	pairs = append(pairs, [2]interface{}{role1.Annotations, role2.Annotations})
-	pairs = append(pairs, [2]interface{}{role1.Rules, role2.Rules})
-	equal := EqualPairs(pairs)
-	if !equal {
-		logger.Info("Resources are not equal", "deployed", deployed, "requested", requested)
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", role1.Name, role2.Name})
+	pairs = append(pairs, fieldPair{"metadata.namespace", role1.Namespace, role2.Namespace})
+	pairs = append(pairs, fieldPair{"metadata.labels", role1.Labels, role2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", role1.Annotations, role2.Annotations})
+	pairs = append(pairs, fieldPair{"rules", role1.Rules, role2.Rules})
+	return diffResources(deployed, pairs)
+}
+
+func equalClusterRoles(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	return len(diffClusterRoles(deployed, requested)) == 0
+}
+
+func diffClusterRoles(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
+	role1 := deployed.(*rbacv1.ClusterRole)
+	role2 := requested.(*rbacv1.ClusterRole)
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", role1.Name, role2.Name})
+	pairs = append(pairs, fieldPair{"metadata.labels", role1.Labels, role2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", role1.Annotations, role2.Annotations})
+	pairs = append(pairs, fieldPair{"aggregationRule", role1.AggregationRule, role2.AggregationRule})
+	if role2.AggregationRule == nil {
+		//Rules of an aggregated cluster role are filled in by the controller manager
+		pairs = append(pairs, fieldPair{"rules", role1.Rules, role2.Rules})
 	}
-	return equal
+	return diffResources(deployed, pairs)
 }
 
 func equalServiceAccounts(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	return len(diffServiceAccounts(deployed, requested)) == 0
+}
+
+func diffServiceAccounts(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
 	sa1 := deployed.(*corev1.ServiceAccount)
 	sa2 := requested.(*corev1.ServiceAccount)
-	var pairs [][2]interface{}
-	pairs = append(pairs, [2]interface{}{sa1.Name, sa2.Name})
-	pairs = append(pairs, [2]interface{}{sa1.Namespace, sa2.Namespace})
-	pairs = append(pairs, [2]interface{}{sa1.Labels, sa2.Labels})
-	pairs = append(pairs, [2]interface{}{sa1.Annotations, sa2.Annotations})
-	equal := EqualPairs(pairs)
-	if !equal {
-		logger.Info("Resources are not equal", "deployed", deployed, "requested", requested)
-	}
-	return equal
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", sa1.Name, sa2.Name})
+	pairs = append(pairs, fieldPair{"metadata.namespace", sa1.Namespace, sa2.Namespace})
+	pairs = append(pairs, fieldPair{"metadata.labels", sa1.Labels, sa2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", sa1.Annotations, sa2.Annotations})
+	return diffResources(deployed, pairs)
 }
 
 func equalRoleBindings(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	return len(diffRoleBindings(deployed, requested)) == 0
+}
+
+func diffRoleBindings(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
 	binding1 := deployed.(*rbacv1.RoleBinding)
 	binding2 := requested.(*rbacv1.RoleBinding)
-	var pairs [][2]interface{}
-	pairs = append(pairs, [2]interface{}{binding1.Name, binding2.Name})
-	pairs = append(pairs, [2]interface{}{binding1.Namespace, binding2.Namespace})
-	pairs = append(pairs, [2]interface{}{binding1.Labels, binding2.Labels})
-	pairs = append(pairs, [2]interface{}{binding1.Annotations, binding2.Annotations})
-	pairs = append(pairs, [2]interface{}{binding1.Subjects, binding2.Subjects})
-	pairs = append(pairs, [2]interface{}{binding1.RoleRef.Name, binding2.RoleRef.Name})
-	equal := EqualPairs(pairs)
-	if !equal {
-		logger.Info("Resources are not equal", "deployed", deployed, "requested", requested)
-	}
-	return equal
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", binding1.Name, binding2.Name})
+	pairs = append(pairs, fieldPair{"metadata.namespace", binding1.Namespace, binding2.Namespace})
+	pairs = append(pairs, fieldPair{"metadata.labels", binding1.Labels, binding2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", binding1.Annotations, binding2.Annotations})
+	pairs = append(pairs, fieldPair{"subjects", binding1.Subjects, binding2.Subjects})
+	pairs = append(pairs, fieldPair{"roleRef.name", binding1.RoleRef.Name, binding2.RoleRef.Name})
+	return diffResources(deployed, pairs)
+}
+
+func equalClusterRoleBindings(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	return len(diffClusterRoleBindings(deployed, requested)) == 0
+}
+
+func diffClusterRoleBindings(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
+	binding1 := deployed.(*rbacv1.ClusterRoleBinding)
+	binding2 := requested.(*rbacv1.ClusterRoleBinding)
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", binding1.Name, binding2.Name})
+	pairs = append(pairs, fieldPair{"metadata.labels", binding1.Labels, binding2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", binding1.Annotations, binding2.Annotations})
+	pairs = append(pairs, fieldPair{"subjects", binding1.Subjects, binding2.Subjects})
+	pairs = append(pairs, fieldPair{"roleRef.name", binding1.RoleRef.Name, binding2.RoleRef.Name})
+	return diffResources(deployed, pairs)
 }
 
 func equalSecrets(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	return len(diffSecrets(deployed, requested)) == 0
+}
+
+func diffSecrets(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
 	secret1 := deployed.(*corev1.Secret)
 	secret2 := requested.(*corev1.Secret)
 	secret1 = mergeSecretStringDataToData(secret1)
 	secret2 = mergeSecretStringDataToData(secret2)
-	var pairs [][2]interface{}
-	pairs = append(pairs, [2]interface{}{secret1.Name, secret2.Name})
-	pairs = append(pairs, [2]interface{}{secret1.Namespace, secret2.Namespace})
-	pairs = append(pairs, [2]interface{}{secret1.Labels, secret2.Labels})
-	pairs = append(pairs, [2]interface{}{secret1.Annotations, secret2.Annotations})
-	pairs = append(pairs, [2]interface{}{secret1.Data, secret2.Data})
-	equal := EqualPairs(pairs)
-	if !equal {
-		logger.Info("Resources are not equal", "deployed", deployed, "requested", requested)
-	}
-	return equal
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", secret1.Name, secret2.Name})
+	pairs = append(pairs, fieldPair{"metadata.namespace", secret1.Namespace, secret2.Namespace})
+	pairs = append(pairs, fieldPair{"metadata.labels", secret1.Labels, secret2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", secret1.Annotations, secret2.Annotations})
+	pairs = append(pairs, fieldPair{"data", secret1.Data, secret2.Data})
+	return diffResources(deployed, pairs)
 }
 
 func mergeSecretStringDataToData(secret *corev1.Secret) *corev1.Secret {
@@ -546,7 +1094,68 @@ func mergeSecretStringDataToData(secret *corev1.Secret) *corev1.Secret {
 	return s
 }
 
+func equalConfigMaps(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	return len(diffConfigMaps(deployed, requested)) == 0
+}
+
+func diffConfigMaps(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
+	cm1 := deployed.(*corev1.ConfigMap)
+	cm2 := requested.(*corev1.ConfigMap)
+	cm1 = cm1.DeepCopy()
+
+	//Empty data is treated like empty annotations and labels
+	ignoreEmptyMaps(cm1, cm2)
+	if len(cm1.Data) == 0 && len(cm2.Data) == 0 {
+		cm1.Data = cm2.Data
+	}
+	if len(cm1.BinaryData) == 0 && len(cm2.BinaryData) == 0 {
+		cm1.BinaryData = cm2.BinaryData
+	}
+
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", cm1.Name, cm2.Name})
+	pairs = append(pairs, fieldPair{"metadata.namespace", cm1.Namespace, cm2.Namespace})
+	pairs = append(pairs, fieldPair{"metadata.labels", cm1.Labels, cm2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", cm1.Annotations, cm2.Annotations})
+	pairs = append(pairs, fieldPair{"data", cm1.Data, cm2.Data})
+	pairs = append(pairs, fieldPair{"binaryData", cm1.BinaryData, cm2.BinaryData})
+	return diffResources(deployed, pairs)
+}
+
+func equalPersistentVolumeClaims(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	return len(diffPersistentVolumeClaims(deployed, requested)) == 0
+}
+
+// diffPersistentVolumeClaims only compares the fields of a claim that can be changed once it is created
+// the requested storage may be changed to expand the volume, while other spec fields are immutable
+func diffPersistentVolumeClaims(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
+	pvc1 := deployed.(*corev1.PersistentVolumeClaim)
+	pvc2 := requested.(*corev1.PersistentVolumeClaim)
+	pvc1 = pvc1.DeepCopy()
+
+	//Removed generated fields from deployed version, that are not specified in requested item
+	for _, annotation := range resource.ClaimBindAnnotations {
+		if _, ok := pvc2.Annotations[annotation]; !ok {
+			delete(pvc1.Annotations, annotation)
+		}
+	}
+
+	ignoreEmptyMaps(pvc1, pvc2)
+
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", pvc1.Name, pvc2.Name})
+	pairs = append(pairs, fieldPair{"metadata.namespace", pvc1.Namespace, pvc2.Namespace})
+	pairs = append(pairs, fieldPair{"metadata.labels", pvc1.Labels, pvc2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", pvc1.Annotations, pvc2.Annotations})
+	pairs = append(pairs, fieldPair{"spec.resources.requests", pvc1.Spec.Resources.Requests, pvc2.Spec.Resources.Requests})
+	return diffResources(deployed, pairs)
+}
+
 func equalBuildConfigs(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	return len(diffBuildConfigs(deployed, requested)) == 0
+}
+
+func diffBuildConfigs(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
 	bc1 := deployed.(*buildv1.BuildConfig)
 	bc2 := requested.(*buildv1.BuildConfig)
 
@@ -558,7 +1167,7 @@ func equalBuildConfigs(deployed resource.KubernetesResource, requested resource.
 	}
 	for i := range bc1.Spec.Triggers {
 		if len(bc1.Spec.Triggers) <= i {
-			return false
+			return []Difference{{Path: "spec.triggers", Deployed: bc1.Spec.Triggers, Requested: bc2.Spec.Triggers}}
 		}
 		trigger1 := bc1.Spec.Triggers[i]
 		trigger2 := bc2.Spec.Triggers[i]
@@ -592,28 +1201,131 @@ func equalBuildConfigs(deployed resource.KubernetesResource, requested resource.
 	ignoreEmptyMaps(bc1, bc2)
 	sortBuildConfigVars(bc1, bc2)
 
-	var pairs [][2]interface{}
-	pairs = append(pairs, [2]interface{}{bc1.Name, bc2.Name})
-	pairs = append(pairs, [2]interface{}{bc1.Namespace, bc2.Namespace})
-	pairs = append(pairs, [2]interface{}{bc1.Labels, bc2.Labels})
-	pairs = append(pairs, [2]interface{}{bc1.Annotations, bc2.Annotations})
-	pairs = append(pairs, [2]interface{}{bc1.Spec, bc2.Spec})
-	equal := EqualPairs(pairs)
-	if !equal {
-		logger.Info("Resources are not equal", "deployed", deployed, "requested", requested)
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", bc1.Name, bc2.Name})
+	pairs = append(pairs, fieldPair{"metadata.namespace", bc1.Namespace, bc2.Namespace})
+	pairs = append(pairs, fieldPair{"metadata.labels", bc1.Labels, bc2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", bc1.Annotations, bc2.Annotations})
+	pairs = append(pairs, fieldPair{"spec", bc1.Spec, bc2.Spec})
+	return diffResources(deployed, pairs)
+}
+
+func equalImageStreams(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	return len(diffImageStreams(deployed, requested)) == 0
+}
+
+// diffImageStreams compares the source and policies of each tag, leaving other tag and stream fields to the image controllers
+func diffImageStreams(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
+	is1 := deployed.(*imagev1.ImageStream)
+	is2 := requested.(*imagev1.ImageStream)
+	is1 = is1.DeepCopy()
+
+	//Removed generated fields from deployed version, that are not specified in requested item
+	if _, ok := is2.Annotations[imageRepositoryCheckAnnotation]; !ok {
+		delete(is1.Annotations, imageRepositoryCheckAnnotation)
 	}
-	return equal
+
+	ignoreEmptyMaps(is1, is2)
+
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", is1.Name, is2.Name})
+	pairs = append(pairs, fieldPair{"metadata.namespace", is1.Namespace, is2.Namespace})
+	pairs = append(pairs, fieldPair{"metadata.labels", is1.Labels, is2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", is1.Annotations, is2.Annotations})
+	pairs = append(pairs, fieldPair{"spec.tags", getTagFields(is1.Spec.Tags, is2.Spec.Tags), getTagFields(is2.Spec.Tags, nil)})
+	return diffResources(deployed, pairs)
+}
+
+type tagFields struct {
+	From            *corev1.ObjectReference    `json:"from"`
+	ImportPolicy    imagev1.TagImportPolicy    `json:"importPolicy"`
+	ReferencePolicy imagev1.TagReferencePolicy `json:"referencePolicy"`
+}
+
+func getTagFields(tags []imagev1.TagReference, requestedTags []imagev1.TagReference) map[string]tagFields {
+	requestedMap := make(map[string]imagev1.TagReference)
+	for _, tag := range requestedTags {
+		requestedMap[tag.Name] = tag
+	}
+	fieldMap := make(map[string]tagFields)
+	for _, tag := range tags {
+		fields := tagFields{From: tag.From, ImportPolicy: tag.ImportPolicy, ReferencePolicy: tag.ReferencePolicy}
+		if requestedTag, ok := requestedMap[tag.Name]; ok {
+			//Removed defaulted values from deployed tag, when not specified in requested tag
+			if requestedTag.ReferencePolicy.Type == "" {
+				fields.ReferencePolicy.Type = ""
+			}
+			if fields.From != nil && requestedTag.From != nil && requestedTag.From.Namespace == "" {
+				from := *fields.From
+				from.Namespace = ""
+				fields.From = &from
+			}
+		}
+		fieldMap[tag.Name] = fields
+	}
+	return fieldMap
+}
+
+func equalCustomResourceDefinitions(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	return len(diffCustomResourceDefinitions(deployed, requested)) == 0
+}
+
+func diffCustomResourceDefinitions(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
+	crd1 := deployed.(*apiextensionsv1beta1.CustomResourceDefinition)
+	crd2 := requested.(*apiextensionsv1beta1.CustomResourceDefinition)
+	crd1 = crd1.DeepCopy()
+
+	//Removed values defaulted by the API server from deployed version, when not specified in requested item
+	if crd2.Spec.Scope == "" {
+		crd1.Spec.Scope = ""
+	}
+	if crd2.Spec.Names.Singular == "" {
+		crd1.Spec.Names.Singular = ""
+	}
+	if crd2.Spec.Names.ListKind == "" {
+		crd1.Spec.Names.ListKind = ""
+	}
+	if crd2.Spec.Version == "" {
+		crd1.Spec.Version = ""
+	}
+	if len(crd2.Spec.Versions) == 0 {
+		crd1.Spec.Versions = crd2.Spec.Versions
+	}
+	if crd2.Spec.Conversion == nil {
+		crd1.Spec.Conversion = nil
+	}
+	ignoreEmptyMaps(crd1, crd2)
+
+	var pairs []fieldPair
+	pairs = append(pairs, fieldPair{"metadata.name", crd1.Name, crd2.Name})
+	pairs = append(pairs, fieldPair{"metadata.labels", crd1.Labels, crd2.Labels})
+	pairs = append(pairs, fieldPair{"metadata.annotations", crd1.Annotations, crd2.Annotations})
+	pairs = append(pairs, fieldPair{"spec", crd1.Spec, crd2.Spec})
+	return diffResources(deployed, pairs)
 }
 
 func deepEquals(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
-	struct1 := reflect.ValueOf(deployed).Elem().Type()
-	if field1, found1 := struct1.FieldByName("Spec"); found1 {
-		struct2 := reflect.ValueOf(requested).Elem().Type()
-		if field2, found2 := struct2.FieldByName("Spec"); found2 {
-			return Equals(field1, field2)
+	return len(deepDiff(deployed, requested)) == 0
+}
+
+// deepDiff compares the Spec field of two resources of the same type, or the whole resources if they have no Spec field
+func deepDiff(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
+	value1 := reflect.ValueOf(deployed).Elem()
+	value2 := reflect.ValueOf(requested).Elem()
+	if value1.Type() == value2.Type() {
+		if spec1 := value1.FieldByName("Spec"); spec1.IsValid() {
+			return diffResources(deployed, []fieldPair{{"spec", spec1.Interface(), value2.FieldByName("Spec").Interface()}})
 		}
 	}
-	return Equals(deployed, requested)
+	return diffResources(deployed, []fieldPair{{"", deployed, requested}})
+}
+
+func diffResources(deployed resource.KubernetesResource, pairs []fieldPair) []Difference {
+	diffs := diffPairs(pairs)
+	if len(diffs) > 0 {
+		logger.Info("Resources are not equal", "kind", reflect.ValueOf(deployed).Elem().Type().Name(), "namespace", deployed.GetNamespace(), "name", deployed.GetName(), "differences", diffs)
+	}
+	return diffs
 }
 
 func EqualPairs(objects [][2]interface{}) bool {
@@ -633,11 +1345,13 @@ func Equals(deployed interface{}, requested interface{}) bool {
 	return equal
 }
 
+// ignoreEmptyMaps treats nil and empty annotations and labels alike, whichever side is empty
+// only the deployed object is changed, so it should be a copy
 func ignoreEmptyMaps(deployed metav1.Object, requested metav1.Object) {
-	if requested.GetAnnotations() == nil && deployed.GetAnnotations() != nil && len(deployed.GetAnnotations()) == 0 {
-		deployed.SetAnnotations(nil)
+	if len(requested.GetAnnotations()) == 0 && len(deployed.GetAnnotations()) == 0 {
+		deployed.SetAnnotations(requested.GetAnnotations())
 	}
-	if requested.GetLabels() == nil && deployed.GetLabels() != nil && len(deployed.GetLabels()) == 0 {
-		deployed.SetLabels(nil)
+	if len(requested.GetLabels()) == 0 && len(deployed.GetLabels()) == 0 {
+		deployed.SetLabels(requested.GetLabels())
 	}
 }
@@ -2,13 +2,28 @@ package compare
 
 import (
 	"fmt"
+	"github.com/RHsyseng/operator-utils/pkg/resource"
 	utils "github.com/RHsyseng/operator-utils/pkg/resource/test"
 	oappsv1 "github.com/openshift/api/apps/v1"
 	obuildv1 "github.com/openshift/api/build/v1"
+	imagev1 "github.com/openshift/api/image/v1"
 	routev1 "github.com/openshift/api/route/v1"
 	"github.com/stretchr/testify/assert"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"reflect"
 	"testing"
 )
@@ -46,6 +61,13 @@ func TestCompareServices(t *testing.T) {
 	assert.False(t, reflect.DeepEqual(services[0], services[1]), "Inconsequential differences between two services should make equality test fail")
 	assert.True(t, deepEquals(&services[0], &services[1]), "Expected resources to be deemed equal")
 	assert.True(t, equalServices(&services[0], &services[1]), "Expected resources to be deemed equal based on service comparator")
+
+	services[1].Spec.Type = corev1.ServiceTypeNodePort
+	assert.False(t, deepEquals(&services[0], &services[1]), "Expected a different spec to make resources unequal")
+	equal, diffs := SimpleComparator().CompareWithDiff(&services[0], &services[1])
+	assert.False(t, equal, "Expected the simple comparator to find the spec difference")
+	assert.Len(t, diffs, 1, "Expected a single difference")
+	assert.Equal(t, "spec.type", diffs[0].Path)
 }
 
 func TestCompareDeploymentConfigs(t *testing.T) {
@@ -57,7 +79,7 @@ func TestCompareDeploymentConfigs(t *testing.T) {
 
 	assert.False(t, reflect.DeepEqual(dcs[0], dcs[1]), "Inconsequential differences between two DCs should make equality test fail")
 	assert.True(t, deepEquals(&dcs[0], &dcs[1]), "Expected resources to be deemed equal")
-	assert.True(t, equalDeploymentConfigs(&dcs[0], &dcs[1]), "Expected resources to be deemed equal based on DC comparator")
+	assert.True(t, equalDeploymentConfigs(&dcs[0], &dcs[1], nil), "Expected resources to be deemed equal based on DC comparator")
 }
 
 func TestCompareEmptyAnnotations(t *testing.T) {
@@ -69,6 +91,26 @@ func TestCompareEmptyAnnotations(t *testing.T) {
 	assert.True(t, equalRoutes(&routes[0], &routes[1]), "Routes should be considered equal")
 }
 
+func TestCompareEmptyMapsOnEitherSide(t *testing.T) {
+	deployments := utils.GetDeployments(2)
+	deployments[1].Name = deployments[0].Name
+	configMaps := []corev1.ConfigMap{{ObjectMeta: metav1.ObjectMeta{Name: "cm"}}, {ObjectMeta: metav1.ObjectMeta{Name: "cm"}}}
+	for _, emptyOnDeployed := range []bool{true, false} {
+		deployed, requested := 0, 1
+		if !emptyOnDeployed {
+			deployed, requested = 1, 0
+		}
+		deployments[deployed].Labels = map[string]string{}
+		deployments[requested].Labels = nil
+		configMaps[deployed].Annotations = map[string]string{}
+		configMaps[deployed].Data = map[string]string{}
+		configMaps[requested].Annotations = nil
+		configMaps[requested].Data = nil
+		assert.True(t, equalDeployment(&deployments[deployed], &deployments[requested], nil), "Expected empty and nil labels to be equal")
+		assert.True(t, equalConfigMaps(&configMaps[deployed], &configMaps[requested]), "Expected empty and nil maps to be equal")
+	}
+}
+
 func TestCompareDeploymentConfigLastTriggeredImage(t *testing.T) {
 	dcs := utils.GetDeploymentConfigs(2)
 	dcs[1].Name = dcs[0].Name
@@ -91,7 +133,7 @@ func TestCompareDeploymentConfigLastTriggeredImage(t *testing.T) {
 			},
 		},
 	}
-	assert.True(t, equalDeploymentConfigs(&dcs[0], &dcs[1]), "Expected resources to be deemed equal based on DC comparator")
+	assert.True(t, equalDeploymentConfigs(&dcs[0], &dcs[1], nil), "Expected resources to be deemed equal based on DC comparator")
 }
 
 func TestCompareDeploymentConfigImageChange(t *testing.T) {
@@ -141,7 +183,7 @@ func TestCompareDeploymentConfigImageChange(t *testing.T) {
 			Image: "image",
 		},
 	}
-	assert.True(t, equalDeploymentConfigs(&dcs[0], &dcs[1]), "Expected resources to be deemed equal based on DC comparator")
+	assert.True(t, equalDeploymentConfigs(&dcs[0], &dcs[1], nil), "Expected resources to be deemed equal based on DC comparator")
 }
 
 func TestCompareBuildConfigWebHooks(t *testing.T) {
@@ -191,7 +233,7 @@ func TestCompareDeployments(t *testing.T) {
 
 	assert.False(t, reflect.DeepEqual(deployments[0], deployments[1]), "Inconsequential differences between two Deployments should make equality test fail")
 	assert.True(t, deepEquals(&deployments[0], &deployments[1]), "Expected resources to be deemed equal")
-	assert.True(t, equalDeployment(&deployments[0], &deployments[1]), "Expected resources to be deemed equal based on Deployment comparator")
+	assert.True(t, equalDeployment(&deployments[0], &deployments[1], nil), "Expected resources to be deemed equal based on Deployment comparator")
 }
 
 func TestCompareDeploymentLastTriggeredImage(t *testing.T) {
@@ -210,7 +252,7 @@ func TestCompareDeploymentLastTriggeredImage(t *testing.T) {
 	deployments[1].Spec.Template.Spec.Containers = []corev1.Container{
 		{Name: "my-container", Image: "quay.io/namespace/image:tag"},
 	}
-	assert.True(t, equalDeployment(&deployments[0], &deployments[1]), "Expected resources to be deemed equal based on deployment comparator")
+	assert.True(t, equalDeployment(&deployments[0], &deployments[1], nil), "Expected resources to be deemed equal based on deployment comparator")
 }
 
 func TestCompareDeploymentGenerateValue(t *testing.T) {
@@ -218,7 +260,315 @@ func TestCompareDeploymentGenerateValue(t *testing.T) {
 	deployments[1].Name = deployments[0].Name
 	deployments[0].Spec.Template.Spec.DNSPolicy = corev1.DNSClusterFirst
 
-	assert.True(t, equalDeployment(&deployments[0], &deployments[1]), "Expected resources to be deemed equal based on deployment comparator")
+	assert.True(t, equalDeployment(&deployments[0], &deployments[1], nil), "Expected resources to be deemed equal based on deployment comparator")
+}
+
+func TestCompareStatefulSets(t *testing.T) {
+	statefulSets := utils.GetStatefulSets(2)
+	statefulSets[1].Name = statefulSets[0].Name
+	for index := range statefulSets {
+		statefulSets[index].Spec.Template.Spec.Containers = []corev1.Container{{Name: "db", Image: "quay.io/namespace/db:1.0"}}
+		statefulSets[index].Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{{ObjectMeta: metav1.ObjectMeta{Name: "data"}}}
+	}
+	replicas := int32(1)
+	revisionHistoryLimit := int32(10)
+	partition := int32(0)
+	volumeMode := corev1.PersistentVolumeFilesystem
+	deployed := &statefulSets[0]
+	deployed.Spec.Replicas = &replicas
+	deployed.Spec.RevisionHistoryLimit = &revisionHistoryLimit
+	deployed.Spec.PodManagementPolicy = appsv1.OrderedReadyPodManagement
+	deployed.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{
+		Type:          appsv1.RollingUpdateStatefulSetStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: &partition},
+	}
+	deployed.Spec.VolumeClaimTemplates[0].Spec.VolumeMode = &volumeMode
+	deployed.Spec.VolumeClaimTemplates[0].Status.Phase = corev1.ClaimPending
+	deployed.Spec.Template.Spec.RestartPolicy = corev1.RestartPolicyAlways
+	deployed.Spec.Template.Spec.Containers[0].TerminationMessagePath = "/dev/termination-log"
+	deployed.Status.ReadyReplicas = 1
+
+	assert.False(t, reflect.DeepEqual(statefulSets[0], statefulSets[1]), "Inconsequential differences between two StatefulSets should make equality test fail")
+	assert.True(t, equalStatefulSets(&statefulSets[0], &statefulSets[1], nil), "Expected resources to be deemed equal based on StatefulSet comparator")
+
+	statefulSets[1].Spec.Template.Spec.Containers[0].Image = "quay.io/namespace/db:2.0"
+	diffs := diffStatefulSets(&statefulSets[0], &statefulSets[1], nil)
+	assert.Len(t, diffs, 1, "Expected image change to be detected")
+	assert.Equal(t, "spec.template.spec.containers[0].image", diffs[0].Path)
+}
+
+func TestCompareDaemonSets(t *testing.T) {
+	requested := appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "daemon", Namespace: "namespace"},
+		Spec: appsv1.DaemonSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "agent", Image: "quay.io/namespace/agent:1.0"}}},
+			},
+		},
+	}
+	revisionHistoryLimit := int32(10)
+	deployed := requested.DeepCopy()
+	deployed.Spec.RevisionHistoryLimit = &revisionHistoryLimit
+	deployed.Spec.UpdateStrategy = appsv1.DaemonSetUpdateStrategy{Type: appsv1.RollingUpdateDaemonSetStrategyType, RollingUpdate: &appsv1.RollingUpdateDaemonSet{}}
+	deployed.Spec.Template.Spec.SchedulerName = "default-scheduler"
+	deployed.Status.NumberReady = 1
+
+	assert.True(t, equalDaemonSets(deployed, &requested), "Expected resources to be deemed equal based on DaemonSet comparator")
+	requested.Spec.Template.Spec.Containers[0].Image = "quay.io/namespace/agent:2.0"
+	assert.False(t, equalDaemonSets(deployed, &requested), "Expected image change to be detected")
+}
+
+func TestCompareJobs(t *testing.T) {
+	requested := batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "job", Namespace: "namespace"},
+		Spec: batchv1.JobSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers:    []corev1.Container{{Name: "task", Image: "quay.io/namespace/task:1.0"}},
+					RestartPolicy: corev1.RestartPolicyNever,
+				},
+			},
+		},
+	}
+	one := int32(1)
+	backoffLimit := int32(6)
+	generatedLabels := map[string]string{"controller-uid": "1234", "job-name": "job"}
+	deployed := requested.DeepCopy()
+	deployed.Labels = generatedLabels
+	deployed.Spec.Parallelism = &one
+	deployed.Spec.Completions = &one
+	deployed.Spec.BackoffLimit = &backoffLimit
+	deployed.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"controller-uid": "1234"}}
+	deployed.Spec.Template.Labels = generatedLabels
+	deployed.Spec.Template.Spec.Containers[0].TerminationMessagePolicy = corev1.TerminationMessageReadFile
+	deployed.Status.Succeeded = 1
+
+	assert.True(t, equalJobs(deployed, &requested), "Expected resources to be deemed equal based on Job comparator")
+	assert.Len(t, deployed.Labels, 2, "Expected comparator to leave deployed object unchanged")
+	requested.Spec.Template.Spec.Containers[0].Image = "quay.io/namespace/task:2.0"
+	diffs := diffJobs(deployed, &requested)
+	assert.Len(t, diffs, 1, "Expected image change to be detected")
+	assert.Equal(t, "spec.template.spec.containers[0].image", diffs[0].Path)
+}
+
+func TestCompareCronJobs(t *testing.T) {
+	requested := batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "cron", Namespace: "namespace"},
+		Spec: batchv1beta1.CronJobSpec{
+			Schedule: "*/5 * * * *",
+			JobTemplate: batchv1beta1.JobTemplateSpec{
+				Spec: batchv1.JobSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "task", Image: "quay.io/namespace/task:1.0"}}},
+					},
+				},
+			},
+		},
+	}
+	suspend := false
+	successfulJobsHistoryLimit := int32(3)
+	failedJobsHistoryLimit := int32(1)
+	deployed := requested.DeepCopy()
+	deployed.Spec.ConcurrencyPolicy = batchv1beta1.AllowConcurrent
+	deployed.Spec.Suspend = &suspend
+	deployed.Spec.SuccessfulJobsHistoryLimit = &successfulJobsHistoryLimit
+	deployed.Spec.FailedJobsHistoryLimit = &failedJobsHistoryLimit
+	deployed.Spec.JobTemplate.Spec.Template.Spec.DNSPolicy = corev1.DNSClusterFirst
+
+	assert.True(t, equalCronJobs(deployed, &requested), "Expected resources to be deemed equal based on CronJob comparator")
+	requested.Spec.Schedule = "*/10 * * * *"
+	assert.False(t, equalCronJobs(deployed, &requested), "Expected schedule change to be detected")
+}
+
+func TestCompareIngresses(t *testing.T) {
+	requested := extensionsv1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "ingress",
+			Namespace:   "namespace",
+			Annotations: map[string]string{"kubernetes.io/ingress.class": ""},
+		},
+		Spec: extensionsv1beta1.IngressSpec{
+			Backend: &extensionsv1beta1.IngressBackend{ServiceName: "service", ServicePort: intstr.FromInt(8080)},
+		},
+	}
+	deployed := requested.DeepCopy()
+	deployed.Annotations = map[string]string{"ingress.kubernetes.io/backends": "{}"}
+	deployed.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "127.0.0.1"}}
+
+	assert.True(t, equalIngresses(deployed, &requested), "Expected resources to be deemed equal based on Ingress comparator")
+	assert.Len(t, deployed.Annotations, 1, "Expected comparator to leave deployed object unchanged")
+	requested.Spec.Backend.ServicePort = intstr.FromInt(8443)
+	diffs := diffIngresses(deployed, &requested)
+	assert.Len(t, diffs, 1, "Expected port change to be detected")
+	assert.Equal(t, "spec.backend.servicePort", diffs[0].Path)
+}
+
+func TestCompareNetworkPolicies(t *testing.T) {
+	port := intstr.FromInt(8080)
+	requested := networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy", Namespace: "namespace"},
+		Spec: networkingv1.NetworkPolicySpec{
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{Ports: []networkingv1.NetworkPolicyPort{{Port: &port}}}},
+		},
+	}
+	protocol := corev1.ProtocolTCP
+	deployed := requested.DeepCopy()
+	deployed.Spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+	deployed.Spec.Ingress[0].Ports[0].Protocol = &protocol
+
+	assert.True(t, equalNetworkPolicies(deployed, &requested), "Expected resources to be deemed equal based on NetworkPolicy comparator")
+	requested.Spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress}
+	assert.False(t, equalNetworkPolicies(deployed, &requested), "Expected policy type change to be detected")
+}
+
+func TestCompareClusterRoles(t *testing.T) {
+	rules := []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list"}}}
+	requested := rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "role"},
+		Rules:      rules,
+	}
+	deployed := requested.DeepCopy()
+	deployed.ResourceVersion = "1234"
+	assert.True(t, equalClusterRoles(deployed, &requested), "Expected resources to be deemed equal based on ClusterRole comparator")
+
+	requested.Rules = nil
+	assert.False(t, equalClusterRoles(deployed, &requested), "Expected rule change to be detected")
+
+	aggregationRule := &rbacv1.AggregationRule{
+		ClusterRoleSelectors: []metav1.LabelSelector{{MatchLabels: map[string]string{"aggregate-to-role": "true"}}},
+	}
+	requested.AggregationRule = aggregationRule
+	deployed.AggregationRule = aggregationRule
+	assert.True(t, equalClusterRoles(deployed, &requested), "Expected rules of aggregated cluster role to be ignored")
+}
+
+func TestCompareClusterRoleBindings(t *testing.T) {
+	requested := rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "binding"},
+		Subjects:   []rbacv1.Subject{{Kind: "ServiceAccount", Name: "operator", Namespace: "namespace"}},
+		RoleRef:    rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "ClusterRole", Name: "role"},
+	}
+	deployed := requested.DeepCopy()
+	deployed.ResourceVersion = "1234"
+	assert.True(t, equalClusterRoleBindings(deployed, &requested), "Expected resources to be deemed equal based on ClusterRoleBinding comparator")
+
+	requested.Subjects[0].Namespace = "other"
+	diffs := diffClusterRoleBindings(deployed, &requested)
+	assert.Len(t, diffs, 1, "Expected subject change to be detected")
+	assert.Equal(t, "subjects[0].namespace", diffs[0].Path)
+}
+
+func TestComparePersistentVolumeClaims(t *testing.T) {
+	requested := corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "claim", Namespace: "namespace"},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: apiresource.MustParse("1Gi")},
+			},
+		},
+	}
+	storageClassName := "standard"
+	deployed := requested.DeepCopy()
+	deployed.Annotations = map[string]string{"pv.kubernetes.io/bind-completed": "yes"}
+	deployed.Spec.VolumeName = "pv1"
+	deployed.Spec.StorageClassName = &storageClassName
+	deployed.Status.Phase = corev1.ClaimBound
+
+	assert.True(t, equalPersistentVolumeClaims(deployed, &requested), "Expected resources to be deemed equal based on PVC comparator")
+	requested.Spec.Resources.Requests[corev1.ResourceStorage] = apiresource.MustParse("2Gi")
+	diffs := diffPersistentVolumeClaims(deployed, &requested)
+	assert.Len(t, diffs, 1, "Expected storage expansion to be detected")
+	assert.Equal(t, "spec.resources.requests[storage]", diffs[0].Path)
+}
+
+func TestCompareHorizontalPodAutoscalers(t *testing.T) {
+	requested := autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "autoscaler", Namespace: "namespace"},
+		Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "deployment1"},
+			MaxReplicas:    10,
+		},
+	}
+	minReplicas := int32(1)
+	deployed := requested.DeepCopy()
+	deployed.Annotations = map[string]string{"autoscaling.alpha.kubernetes.io/conditions": "[]"}
+	deployed.Spec.MinReplicas = &minReplicas
+	deployed.Status.CurrentReplicas = 3
+
+	assert.True(t, equalHorizontalPodAutoscalers(deployed, &requested), "Expected resources to be deemed equal based on HPA comparator")
+	requested.Spec.MaxReplicas = 20
+	assert.False(t, equalHorizontalPodAutoscalers(deployed, &requested), "Expected max replicas change to be detected")
+}
+
+func TestComparePodDisruptionBudgets(t *testing.T) {
+	minAvailable := intstr.FromInt(1)
+	requested := policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "budget", Namespace: "namespace"},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			MinAvailable: &minAvailable,
+			Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "my-app"}},
+		},
+	}
+	deployed := requested.DeepCopy()
+	deployed.Labels = map[string]string{}
+	deployed.Status.CurrentHealthy = 2
+
+	assert.True(t, equalPodDisruptionBudgets(deployed, &requested), "Expected resources to be deemed equal based on PDB comparator")
+	minAvailable = intstr.FromInt(2)
+	assert.False(t, equalPodDisruptionBudgets(deployed, &requested), "Expected min available change to be detected")
+}
+
+func TestCompareImageStreams(t *testing.T) {
+	requested := imagev1.ImageStream{
+		ObjectMeta: metav1.ObjectMeta{Name: "stream", Namespace: "namespace"},
+		Spec: imagev1.ImageStreamSpec{
+			Tags: []imagev1.TagReference{
+				{Name: "latest", From: &corev1.ObjectReference{Kind: "DockerImage", Name: "quay.io/namespace/image:1.0"}},
+			},
+		},
+	}
+	generation := int64(2)
+	deployed := requested.DeepCopy()
+	deployed.Annotations = map[string]string{"openshift.io/image.dockerRepositoryCheck": "2020-01-01T00:00:00Z"}
+	deployed.Spec.DockerImageRepository = "registry/namespace/stream"
+	deployed.Spec.Tags[0].Generation = &generation
+	deployed.Spec.Tags[0].ReferencePolicy.Type = imagev1.SourceTagReferencePolicy
+	deployed.Status.DockerImageRepository = "registry/namespace/stream"
+
+	assert.True(t, equalImageStreams(deployed, &requested), "Expected resources to be deemed equal based on ImageStream comparator")
+	requested.Spec.Tags[0].From.Name = "quay.io/namespace/image:2.0"
+	diffs := diffImageStreams(deployed, &requested)
+	assert.Len(t, diffs, 1, "Expected tag source change to be detected")
+	assert.Equal(t, "spec.tags[latest].from.name", diffs[0].Path)
+}
+
+func TestCompareCustomResourceDefinitions(t *testing.T) {
+	requested := apiextensionsv1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "myapps.example.com"},
+		Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{
+			Group:   "example.com",
+			Version: "v1",
+			Names:   apiextensionsv1beta1.CustomResourceDefinitionNames{Kind: "MyApp", Plural: "myapps"},
+		},
+	}
+	deployed := requested.DeepCopy()
+	deployed.ResourceVersion = "1234"
+	deployed.Spec.Scope = apiextensionsv1beta1.NamespaceScoped
+	deployed.Spec.Names.Singular = "myapp"
+	deployed.Spec.Names.ListKind = "MyAppList"
+	deployed.Spec.Versions = []apiextensionsv1beta1.CustomResourceDefinitionVersion{{Name: "v1", Served: true, Storage: true}}
+	deployed.Spec.Conversion = &apiextensionsv1beta1.CustomResourceConversion{Strategy: apiextensionsv1beta1.NoneConverter}
+	deployed.Status.AcceptedNames = deployed.Spec.Names
+	deployed.Status.StoredVersions = []string{"v1"}
+
+	assert.True(t, equalCustomResourceDefinitions(deployed, &requested), "Expected resources to be deemed equal based on CRD comparator")
+	requested.Spec.Validation = &apiextensionsv1beta1.CustomResourceValidation{
+		OpenAPIV3Schema: &apiextensionsv1beta1.JSONSchemaProps{Type: "object"},
+	}
+	diffs := diffCustomResourceDefinitions(deployed, &requested)
+	assert.Len(t, diffs, 1, "Expected schema change to be detected")
+	assert.Equal(t, "spec.validation", diffs[0].Path)
 }
 
 func TestCompareSecrets(t *testing.T) {
@@ -240,6 +590,34 @@ func TestCompareSecrets(t *testing.T) {
 	assert.True(t, equalSecrets(&secrets[0], &secrets[2]), "Expected resources to be deemed equal based on Secret comparator")
 }
 
+func TestCompareConfigMaps(t *testing.T) {
+	configMap := corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "config",
+			Namespace: "namespace",
+			Labels:    map[string]string{"app": "test"},
+		},
+		Data: map[string]string{"key": "value"},
+	}
+	deployed := configMap.DeepCopy()
+	deployed.ResourceVersion = "1234"
+	deployed.UID = "5678"
+	deployed.Annotations = map[string]string{}
+	deployed.BinaryData = map[string][]byte{}
+
+	assert.False(t, reflect.DeepEqual(deployed, &configMap), "Inconsequential differences between two ConfigMaps should make equality test fail")
+	assert.True(t, equalConfigMaps(deployed, &configMap), "Expected resources to be deemed equal based on ConfigMap comparator")
+
+	configMap.Data["key"] = "changed"
+	diffs := diffConfigMaps(deployed, &configMap)
+	assert.Len(t, diffs, 1, "Expected changed data to be reported")
+	assert.Equal(t, "data[key]", diffs[0].Path)
+
+	configMap.Data = nil
+	configMap.BinaryData = map[string][]byte{"key": []byte("value")}
+	assert.False(t, equalConfigMaps(deployed, &configMap), "Expected binary data to be compared")
+}
+
 func Test_mergeSecretStringDataToData(t *testing.T) {
 	tests := []struct {
 		name string
@@ -319,12 +697,12 @@ func TestCompareUnorderedDeploymentEnvVars(t *testing.T) {
 	deployments[1].Spec.Template.Spec.Containers[0].Env = orderedVars
 
 	assert.True(t, deepEquals(&deployments[0], &deployments[1]), "Has the same EnvVars. Expected resources to be deemed equal")
-	assert.True(t, equalDeployment(&deployments[0], &deployments[1]), "Has the same EnvVars. Expected resources to be deemed equal based on Deployment comparator")
+	assert.True(t, equalDeployment(&deployments[0], &deployments[1], nil), "Has the same EnvVars. Expected resources to be deemed equal based on Deployment comparator")
 
 	deployments[1].Spec.Template.Spec.Containers[0].Env = unorderedVars
 
-	assert.True(t, deepEquals(&deployments[0], &deployments[1]), "Has the same EnvVars, unordered. Expected resources to be deemed equal")
-	assert.True(t, equalDeployment(&deployments[0], &deployments[1]), "Has the same EnvVars, unordered. Expected resources to be deemed equal based on Deployment comparator")
+	assert.False(t, deepEquals(&deployments[0], &deployments[1]), "Has the same EnvVars, unordered. Expected a value comparison to be sensitive to order")
+	assert.True(t, equalDeployment(&deployments[0], &deployments[1], nil), "Has the same EnvVars, unordered. Expected resources to be deemed equal based on Deployment comparator")
 }
 
 func TestCompareUnorderedDeploymentConfigEnvVars(t *testing.T) {
@@ -339,10 +717,145 @@ func TestCompareUnorderedDeploymentConfigEnvVars(t *testing.T) {
 	deployments[1].Spec.Template.Spec.Containers[0].Env = orderedVars
 
 	assert.True(t, deepEquals(&deployments[0], &deployments[1]), "Has the same EnvVars. Expected resources to be deemed equal")
-	assert.True(t, equalDeploymentConfigs(&deployments[0], &deployments[1]), "Has the same EnvVars. Expected resources to be deemed equal based on DeploymentConfig comparator")
+	assert.True(t, equalDeploymentConfigs(&deployments[0], &deployments[1], nil), "Has the same EnvVars. Expected resources to be deemed equal based on DeploymentConfig comparator")
 
 	deployments[1].Spec.Template.Spec.Containers[0].Env = unorderedVars
 
-	assert.True(t, deepEquals(&deployments[0], &deployments[1]), "Has the same EnvVars, unordered. Expected resources to be deemed equal")
-	assert.True(t, equalDeploymentConfigs(&deployments[0], &deployments[1]), "Has the same EnvVars, unordered. Expected resources to be deemed equal based on DeploymentConfig comparator")
+	assert.False(t, deepEquals(&deployments[0], &deployments[1]), "Has the same EnvVars, unordered. Expected a value comparison to be sensitive to order")
+	assert.True(t, equalDeploymentConfigs(&deployments[0], &deployments[1], nil), "Has the same EnvVars, unordered. Expected resources to be deemed equal based on DeploymentConfig comparator")
+}
+
+func TestDiffDeployments(t *testing.T) {
+	deployments := utils.GetDeployments(2)
+	deployments[1].Name = deployments[0].Name
+	deployments[0].Spec.Template.Spec.Containers = []corev1.Container{
+		{Name: "my-container", Image: "quay.io/namespace/image:1.0"},
+	}
+	deployments[1].Spec.Template.Spec.Containers = []corev1.Container{
+		{Name: "my-container", Image: "quay.io/namespace/image:2.0"},
+	}
+	deployments[1].Labels = map[string]string{"app.kubernetes.io/name": "my-app"}
+
+	diffs := diffDeployment(&deployments[0], &deployments[1], nil)
+	assert.Len(t, diffs, 2, "Expected label and image differences only")
+	assert.Equal(t, Difference{Path: "metadata.labels", Deployed: map[string]string(nil), Requested: deployments[1].Labels}, diffs[0])
+	assert.Equal(t, Difference{Path: "spec.template.spec.containers[0].image", Deployed: "quay.io/namespace/image:1.0", Requested: "quay.io/namespace/image:2.0"}, diffs[1])
+	assert.False(t, equalDeployment(&deployments[0], &deployments[1], nil), "Expected resources to be deemed different based on Deployment comparator")
+}
+
+func TestDiffMatchesDeepEquals(t *testing.T) {
+	services := utils.GetServices(2)
+	services[1].Name = services[0].Name
+	assert.Empty(t, Diff(services[0], services[0]), "Expected no differences between identical objects")
+
+	services[0].Spec.Ports = []corev1.ServicePort{}
+	diffs := Diff(services[0], services[1])
+	assert.Len(t, diffs, 1, "Expected an empty slice to differ from a nil slice, just like reflect.DeepEqual")
+	assert.Equal(t, "spec.ports", diffs[0].Path)
+}
+
+func TestCompareSemantic(t *testing.T) {
+	requested := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod1",
+			Namespace: "namespace",
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "my-container", Image: "quay.io/namespace/image:1.0"}},
+		},
+	}
+	deployed := requested.DeepCopy()
+	deployed.ResourceVersion = "1234"
+	deployed.UID = "5678"
+	deployed.Labels = map[string]string{"injected": "true"}
+	deployed.Spec.NodeName = "node1"
+	deployed.Spec.DNSPolicy = corev1.DNSClusterFirst
+	deployed.Spec.Containers[0].TerminationMessagePath = "/dev/termination-log"
+	deployed.Status.Phase = corev1.PodRunning
+
+	assert.True(t, equalSemantic(deployed, &requested), "Expected server-assigned and defaulted values to be ignored")
+	assert.True(t, DefaultComparator().Compare(deployed, &requested), "Expected default comparator to ignore server-assigned and defaulted values")
+
+	requested.Spec.Containers[0].Image = "quay.io/namespace/image:2.0"
+	assert.False(t, equalSemantic(deployed, &requested), "Expected image change to be detected")
+	equal, diffs := DefaultComparator().CompareWithDiff(deployed, &requested)
+	assert.False(t, equal, "Expected image change to be detected")
+	assert.Equal(t, []Difference{{Path: "spec.containers[0].image", Deployed: "quay.io/namespace/image:1.0", Requested: "quay.io/namespace/image:2.0"}}, diffs)
+
+	requested.Spec.Containers[0].Image = "quay.io/namespace/image:1.0"
+	requested.Labels = map[string]string{"app": "my-app"}
+	diffs = diffSemantic(deployed, &requested)
+	assert.Len(t, diffs, 1, "Expected missing label to be detected")
+	assert.Equal(t, "metadata.labels[app]", diffs[0].Path)
+}
+
+func TestCompareUnstructured(t *testing.T) {
+	requested := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "example.com/v1",
+			"kind":       "MyResource",
+			"metadata": map[string]interface{}{
+				"name":      "resource1",
+				"namespace": "namespace",
+			},
+			"spec": map[string]interface{}{
+				"size":    int64(3),
+				"enabled": false,
+			},
+		},
+	}
+	deployed := requested.DeepCopy()
+	deployed.SetResourceVersion("1234")
+	deployed.SetUID("5678")
+	assert.Nil(t, unstructured.SetNestedField(deployed.Object, "defaulted", "spec", "mode"), "Expect no errors setting field")
+	assert.Nil(t, unstructured.SetNestedField(deployed.Object, "Ready", "status", "phase"), "Expect no errors setting field")
+
+	assert.True(t, equalUnstructured(deployed, requested), "Expected server-assigned and defaulted values to be ignored")
+	assert.True(t, defaultMap(nil)[reflect.TypeOf(unstructured.Unstructured{})](deployed, requested), "Expected unstructured comparator to be registered")
+
+	assert.Nil(t, unstructured.SetNestedField(requested.Object, true, "spec", "enabled"), "Expect no errors setting field")
+	assert.Equal(t, []Difference{{Path: "spec.enabled", Deployed: false, Requested: true}}, diffUnstructured(deployed, requested))
+
+	assert.Nil(t, unstructured.SetNestedField(deployed.Object, true, "spec", "enabled"), "Expect no errors setting field")
+	assert.Nil(t, unstructured.SetNestedField(requested.Object, false, "spec", "enabled"), "Expect no errors setting field")
+	assert.Len(t, diffUnstructured(deployed, requested), 1, "Expected explicit false value to be compared")
+}
+
+func TestCompareUnstructuredMap(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "MyResource"}
+	otherGVK := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "OtherResource"}
+	newObject := func(gvk schema.GroupVersionKind, name string, size int64) *unstructured.Unstructured {
+		object := &unstructured.Unstructured{}
+		object.SetGroupVersionKind(gvk)
+		object.SetName(name)
+		object.SetNamespace("namespace")
+		assert.Nil(t, unstructured.SetNestedField(object.Object, size, "spec", "size"), "Expect no errors setting field")
+		return object
+	}
+	deployed := NewMapBuilder().Add(newObject(gvk, "resource1", 1), newObject(gvk, "resource2", 1), newObject(otherGVK, "resource1", 1)).UnstructuredMap()
+	builder := NewMapBuilder().Add(newObject(gvk, "resource1", 2), newObject(gvk, "resource3", 1), newObject(otherGVK, "resource1", 1))
+	assert.Empty(t, builder.ResourceMap(), "Expected unstructured resources to be kept apart from typed resources")
+
+	comparator := NewMapComparator()
+	deltas := comparator.CompareUnstructured(deployed, builder.UnstructuredMap())
+	unchanged := deltas[otherGVK]
+	assert.False(t, unchanged.HasChanges(), "Expected unchanged kind to have no changes")
+	delta := deltas[gvk]
+	assert.Len(t, delta.Added, 1, "Expected one resource to be added")
+	assert.Equal(t, "resource3", delta.Added[0].GetName())
+	assert.Len(t, delta.Updated, 1, "Expected one resource to be updated")
+	assert.Equal(t, "resource1", delta.Updated[0].GetName())
+	assert.Len(t, delta.Removed, 1, "Expected one resource to be removed")
+	assert.Equal(t, "resource2", delta.Removed[0].GetName())
+}
+
+func TestCompareIgnoresLastApplied(t *testing.T) {
+	requested := utils.GetRoutes(1)[0]
+	deployed := requested.DeepCopy()
+	deployed.Annotations = map[string]string{resource.LastAppliedAnnotation: "{}"}
+	assert.True(t, DefaultComparator().Compare(deployed, &requested), "Expected annotation recorded by the writer to be ignored")
+	equal, diffs := DefaultComparator().CompareWithDiff(deployed, &requested)
+	assert.True(t, equal, "Expected annotation recorded by the writer to be ignored")
+	assert.Empty(t, diffs, "Expected no differences")
+	assert.Len(t, deployed.Annotations, 1, "Expected deployed object to be left unchanged")
 }
@@ -0,0 +1,184 @@
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Difference describes a single value found to differ between the deployed and requested version of a resource
+// the path is a JSONPath-like expression based on the json field names of the compared objects
+type Difference struct {
+	Path      string      `json:"path"`
+	Deployed  interface{} `json:"deployed,omitempty"`
+	Requested interface{} `json:"requested,omitempty"`
+}
+
+func (this Difference) String() string {
+	return fmt.Sprintf("%s: %v -> %v", this.Path, this.Deployed, this.Requested)
+}
+
+type fieldPair struct {
+	path      string
+	deployed  interface{}
+	requested interface{}
+}
+
+// Diff walks the two provided objects and returns every leaf value that differs between them
+// no differences are reported if and only if the two objects are deeply equal
+func Diff(deployed interface{}, requested interface{}) []Difference {
+	return appendDiffs(nil, "", reflect.ValueOf(deployed), reflect.ValueOf(requested))
+}
+
+func diffPairs(pairs []fieldPair) []Difference {
+	var diffs []Difference
+	for _, pair := range pairs {
+		diffs = appendDiffs(diffs, pair.path, reflect.ValueOf(pair.deployed), reflect.ValueOf(pair.requested))
+	}
+	return diffs
+}
+
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+func appendDiffs(diffs []Difference, path string, deployed reflect.Value, requested reflect.Value) []Difference {
+	if !deployed.IsValid() || !requested.IsValid() || deployed.Type() != requested.Type() {
+		if !reflect.DeepEqual(valueOf(deployed), valueOf(requested)) {
+			diffs = append(diffs, newDifference(path, deployed, requested))
+		}
+		return diffs
+	}
+	if isLeaf(deployed.Type()) {
+		if !reflect.DeepEqual(deployed.Interface(), requested.Interface()) {
+			diffs = append(diffs, newDifference(path, deployed, requested))
+		}
+		return diffs
+	}
+	switch deployed.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if deployed.IsNil() || requested.IsNil() {
+			if deployed.IsNil() != requested.IsNil() {
+				diffs = append(diffs, newDifference(path, deployed, requested))
+			}
+			return diffs
+		}
+		return appendDiffs(diffs, path, deployed.Elem(), requested.Elem())
+	case reflect.Struct:
+		for index := 0; index < deployed.NumField(); index++ {
+			name, inline := jsonName(deployed.Type().Field(index))
+			fieldPath := path
+			if !inline {
+				fieldPath = joinPath(path, name)
+			}
+			diffs = appendDiffs(diffs, fieldPath, deployed.Field(index), requested.Field(index))
+		}
+		return diffs
+	case reflect.Map:
+		if deployed.IsNil() != requested.IsNil() {
+			return append(diffs, newDifference(path, deployed, requested))
+		}
+		for _, key := range mapKeys(deployed, requested) {
+			keyPath := fmt.Sprintf("%s[%v]", path, key.Interface())
+			diffs = appendDiffs(diffs, keyPath, deployed.MapIndex(key), requested.MapIndex(key))
+		}
+		return diffs
+	case reflect.Slice, reflect.Array:
+		if deployed.Kind() == reflect.Slice && deployed.IsNil() != requested.IsNil() {
+			return append(diffs, newDifference(path, deployed, requested))
+		}
+		length := deployed.Len()
+		if requested.Len() > length {
+			length = requested.Len()
+		}
+		for index := 0; index < length; index++ {
+			var value1, value2 reflect.Value
+			if index < deployed.Len() {
+				value1 = deployed.Index(index)
+			}
+			if index < requested.Len() {
+				value2 = requested.Index(index)
+			}
+			diffs = appendDiffs(diffs, fmt.Sprintf("%s[%d]", path, index), value1, value2)
+		}
+		return diffs
+	default:
+		if !reflect.DeepEqual(deployed.Interface(), requested.Interface()) {
+			diffs = append(diffs, newDifference(path, deployed, requested))
+		}
+		return diffs
+	}
+}
+
+func isLeaf(valueType reflect.Type) bool {
+	if valueType.Implements(marshalerType) {
+		//Types such as Quantity, Time and IntOrString are best compared and reported as a whole
+		return true
+	}
+	if valueType.Kind() == reflect.Slice && valueType.Elem().Kind() == reflect.Uint8 {
+		return true
+	}
+	if valueType.Kind() == reflect.Struct {
+		for index := 0; index < valueType.NumField(); index++ {
+			if valueType.Field(index).PkgPath != "" {
+				//Unexported fields cannot be walked, so compare the struct as a whole
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := strings.Split(field.Tag.Get("json"), ",")
+	if field.Anonymous && tag[0] == "" {
+		return "", true
+	}
+	for _, option := range tag[1:] {
+		if option == "inline" {
+			return "", true
+		}
+	}
+	if tag[0] == "" || tag[0] == "-" {
+		return field.Name, false
+	}
+	return tag[0], false
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func mapKeys(map1 reflect.Value, map2 reflect.Value) []reflect.Value {
+	var keys []reflect.Value
+	for _, key := range map1.MapKeys() {
+		keys = append(keys, key)
+	}
+	for _, key := range map2.MapKeys() {
+		if !map1.MapIndex(key).IsValid() {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	return keys
+}
+
+func newDifference(path string, deployed reflect.Value, requested reflect.Value) Difference {
+	return Difference{
+		Path:      path,
+		Deployed:  valueOf(deployed),
+		Requested: valueOf(requested),
+	}
+}
+
+func valueOf(value reflect.Value) interface{} {
+	if !value.IsValid() || !value.CanInterface() {
+		return nil
+	}
+	return value.Interface()
+}
@@ -2,6 +2,8 @@ package compare
 
 import (
 	"github.com/RHsyseng/operator-utils/pkg/resource"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"reflect"
 	logs "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 )
@@ -10,6 +12,9 @@ var logger = logs.Log.WithName("comparator")
 
 type MapComparator struct {
 	Comparator ResourceComparator
+	//IgnoreAutoscaledReplicas sets the scale targets of the requested HorizontalPodAutoscalers on the comparator before each comparison
+	//so the workload comparators leave out their replica count
+	IgnoreAutoscaledReplicas bool
 }
 
 func NewMapComparator() MapComparator {
@@ -19,6 +24,9 @@ func NewMapComparator() MapComparator {
 }
 
 func (this *MapComparator) Compare(deployed map[reflect.Type][]resource.KubernetesResource, requested map[reflect.Type][]resource.KubernetesResource) map[reflect.Type]ResourceDelta {
+	if this.IgnoreAutoscaledReplicas {
+		this.Comparator.SetAutoscaledTargets(GetAutoscaledTargets(requested[reflect.TypeOf(autoscalingv1.HorizontalPodAutoscaler{})]))
+	}
 	delta := make(map[reflect.Type]ResourceDelta)
 	for deployedType, deployedArray := range deployed {
 		requestedArray := requested[deployedType]
@@ -32,3 +40,19 @@ func (this *MapComparator) Compare(deployed map[reflect.Type][]resource.Kubernet
 	}
 	return delta
 }
+
+// CompareUnstructured compares deployed and requested unstructured resources, organized by GroupVersionKind, like Compare does for typed resources
+func (this *MapComparator) CompareUnstructured(deployed map[schema.GroupVersionKind][]resource.KubernetesResource, requested map[schema.GroupVersionKind][]resource.KubernetesResource) map[schema.GroupVersionKind]ResourceDelta {
+	delta := make(map[schema.GroupVersionKind]ResourceDelta)
+	for deployedKind, deployedArray := range deployed {
+		requestedArray := requested[deployedKind]
+		delta[deployedKind] = this.Comparator.CompareArrays(deployedArray, requestedArray)
+	}
+	for requestedKind, requestedArray := range requested {
+		if _, ok := deployed[requestedKind]; !ok {
+			//Item kind in request does not exist in deployed set, needs to be added:
+			delta[requestedKind] = ResourceDelta{Added: requestedArray}
+		}
+	}
+	return delta
+}
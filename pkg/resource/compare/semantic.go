@@ -0,0 +1,147 @@
+package compare
+
+import (
+	"fmt"
+	"github.com/RHsyseng/operator-utils/pkg/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+)
+
+func equalSemantic(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	return len(diffSemantic(deployed, requested)) == 0
+}
+
+// diffSemantic compares any two resources of the same type, without knowledge of the type itself
+// server-managed metadata, type metadata and status are ignored, and any field left unset in the requested resource is not compared,
+// so values defaulted or assigned by the server do not cause a difference
+// a field is considered unset if it has the zero value of its type, which means a requested false, 0 or empty value is not enforced either
+func diffSemantic(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
+	if reflect.TypeOf(deployed) != reflect.TypeOf(requested) {
+		return Diff(deployed, requested)
+	}
+	requestedCopy := requested.DeepCopyObject().(resource.KubernetesResource)
+	clearServerFields(requestedCopy)
+	diffs := appendSubsetDiffs(nil, "", reflect.ValueOf(deployed), reflect.ValueOf(requestedCopy))
+	if len(diffs) > 0 {
+		logger.Info("Resources are not equal", "kind", reflect.ValueOf(deployed).Elem().Type().Name(), "namespace", deployed.GetNamespace(), "name", deployed.GetName(), "differences", diffs)
+	}
+	return diffs
+}
+
+func clearServerFields(object resource.KubernetesResource) {
+	object.SetResourceVersion("")
+	object.SetUID("")
+	object.SetGeneration(0)
+	object.SetCreationTimestamp(metav1.Time{})
+	object.SetDeletionTimestamp(nil)
+	object.SetSelfLink("")
+	objectValue := reflect.ValueOf(object).Elem()
+	if objectValue.Kind() != reflect.Struct {
+		return
+	}
+	for _, name := range []string{"TypeMeta", "Status"} {
+		field := objectValue.FieldByName(name)
+		if field.IsValid() && field.CanSet() {
+			field.Set(reflect.Zero(field.Type()))
+		}
+	}
+}
+
+func appendSubsetDiffs(diffs []Difference, path string, deployed reflect.Value, requested reflect.Value) []Difference {
+	if !requested.IsValid() || isZero(requested) {
+		//Not set in the requested object, so any deployed value is acceptable
+		return diffs
+	}
+	if !deployed.IsValid() || deployed.Type() != requested.Type() || isLeaf(requested.Type()) {
+		if !reflect.DeepEqual(valueOf(deployed), valueOf(requested)) {
+			diffs = append(diffs, newDifference(path, deployed, requested))
+		}
+		return diffs
+	}
+	switch requested.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if deployed.IsNil() {
+			return append(diffs, newDifference(path, deployed, requested))
+		}
+		return appendSubsetDiffs(diffs, path, deployed.Elem(), requested.Elem())
+	case reflect.Struct:
+		for index := 0; index < requested.NumField(); index++ {
+			name, inline := jsonName(requested.Type().Field(index))
+			fieldPath := path
+			if !inline {
+				fieldPath = joinPath(path, name)
+			}
+			diffs = appendSubsetDiffs(diffs, fieldPath, deployed.Field(index), requested.Field(index))
+		}
+		return diffs
+	case reflect.Map:
+		for _, key := range mapKeys(requested, reflect.MakeMap(requested.Type())) {
+			keyPath := mapKeyPath(path, requested.Type(), key)
+			deployedValue := deployed.MapIndex(key)
+			requestedValue := requested.MapIndex(key)
+			if !deployedValue.IsValid() {
+				diffs = append(diffs, newDifference(keyPath, deployedValue, requestedValue))
+				continue
+			}
+			if isJSONObject(requested.Type()) && isZeroScalar(requestedValue) {
+				//A zero value that is present in a generic JSON object was set explicitly, and is compared as such
+				if !reflect.DeepEqual(valueOf(deployedValue), valueOf(requestedValue)) {
+					diffs = append(diffs, newDifference(keyPath, deployedValue, requestedValue))
+				}
+				continue
+			}
+			diffs = appendSubsetDiffs(diffs, keyPath, deployedValue, requestedValue)
+		}
+		return diffs
+	case reflect.Slice, reflect.Array:
+		if deployed.Len() != requested.Len() {
+			//Items cannot be matched up, so report the list as a whole
+			return append(diffs, newDifference(path, deployed, requested))
+		}
+		for index := 0; index < requested.Len(); index++ {
+			diffs = appendSubsetDiffs(diffs, fmt.Sprintf("%s[%d]", path, index), deployed.Index(index), requested.Index(index))
+		}
+		return diffs
+	default:
+		if !reflect.DeepEqual(deployed.Interface(), requested.Interface()) {
+			diffs = append(diffs, newDifference(path, deployed, requested))
+		}
+		return diffs
+	}
+}
+
+func mapKeyPath(path string, mapType reflect.Type, key reflect.Value) string {
+	if isJSONObject(mapType) {
+		//Generic JSON objects, as found in unstructured resources, hold fields rather than entries
+		return joinPath(path, key.String())
+	}
+	return fmt.Sprintf("%s[%v]", path, key.Interface())
+}
+
+func isJSONObject(mapType reflect.Type) bool {
+	return mapType.Key().Kind() == reflect.String && mapType.Elem().Kind() == reflect.Interface
+}
+
+func isZeroScalar(value reflect.Value) bool {
+	if value.Kind() != reflect.Interface || value.IsNil() {
+		return false
+	}
+	switch value.Elem().Kind() {
+	case reflect.Map, reflect.Slice:
+		return false
+	}
+	return isZero(value.Elem())
+}
+
+func isZero(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	case reflect.Map, reflect.Slice:
+		return value.Len() == 0
+	}
+	if !value.CanInterface() {
+		return false
+	}
+	return reflect.DeepEqual(value.Interface(), reflect.Zero(value.Type()).Interface())
+}
@@ -1,12 +1,17 @@
 package test
 
 import (
+	"fmt"
 	"github.com/RHsyseng/operator-utils/pkg/resource"
 	"github.com/RHsyseng/operator-utils/pkg/resource/compare"
 	"github.com/RHsyseng/operator-utils/pkg/resource/test"
 	oappsv1 "github.com/openshift/api/apps/v1"
 	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	corev1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"reflect"
 	"testing"
 )
@@ -115,3 +120,151 @@ func TestCompareCombined(t *testing.T) {
 	assert.Len(t, deltaMap[dcType].Removed, 1, "Expected 1 removed dc")
 	assert.Equal(t, deltaMap[dcType].Removed[0].GetName(), "dc3", "Expected removed dc called dc3")
 }
+
+func TestCompareAutoscaledReplicas(t *testing.T) {
+	dcs := test.GetDeploymentConfigs(4)
+	dcs[0].Namespace = "namespace"
+	dcs[1].Namespace = "namespace"
+	dcs[2].Namespace = "namespace"
+	dcs[3].Namespace = "namespace"
+	dcs[1].Name = dcs[0].Name
+	dcs[3].Name = dcs[2].Name
+	dcs[0].Spec.Replicas = 5
+	dcs[1].Spec.Replicas = 2
+	dcs[2].Spec.Replicas = 5
+	dcs[3].Spec.Replicas = 2
+	hpa := autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "autoscaler", Namespace: "namespace"},
+		Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{APIVersion: "apps.openshift.io/v1", Kind: "DeploymentConfig", Name: dcs[0].Name},
+			MaxReplicas:    10,
+		},
+	}
+
+	dcType := reflect.TypeOf(oappsv1.DeploymentConfig{})
+	hpaType := reflect.TypeOf(autoscalingv1.HorizontalPodAutoscaler{})
+	deployed := map[reflect.Type][]resource.KubernetesResource{
+		dcType:  {&dcs[0], &dcs[2]},
+		hpaType: {hpa.DeepCopy()},
+	}
+	requested := map[reflect.Type][]resource.KubernetesResource{
+		dcType:  {&dcs[1], &dcs[3]},
+		hpaType: {&hpa},
+	}
+
+	mapComparator := compare.NewMapComparator()
+	deltaMap := mapComparator.Compare(deployed, requested)
+	assert.Len(t, deltaMap[dcType].Updated, 2, "Expected replica changes to be detected by default")
+
+	mapComparator.IgnoreAutoscaledReplicas = true
+	deltaMap = mapComparator.Compare(deployed, requested)
+	assert.Len(t, deltaMap[dcType].Updated, 1, "Expected replicas of autoscaled dc to be ignored")
+	assert.True(t, deltaMap[dcType].Updated[0] == &dcs[3], "Expected the requested dc that is not autoscaled to be updated, rather than a copy")
+	assert.Empty(t, deltaMap[hpaType].Updated, "Expected no changes to autoscaler")
+	assert.Equal(t, int32(2), dcs[1].Spec.Replicas, "Expected requested object to be left unchanged")
+
+	hpa.Spec.ScaleTargetRef.APIVersion = "apps/v1"
+	deltaMap = mapComparator.Compare(deployed, requested)
+	assert.Len(t, deltaMap[dcType].Updated, 2, "Expected an autoscaler targeting another api group to be ignored")
+}
+
+func TestCompareAutoscaledWorkloads(t *testing.T) {
+	replicas := int32(2)
+	scaledReplicas := int32(5)
+	requestedDeployment := appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "workload", Namespace: "namespace"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+	}
+	deployedDeployment := requestedDeployment.DeepCopy()
+	deployedDeployment.Spec.Replicas = &scaledReplicas
+	requestedStatefulSet := appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "workload", Namespace: "namespace"},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+	}
+	deployedStatefulSet := requestedStatefulSet.DeepCopy()
+	deployedStatefulSet.Spec.Replicas = &scaledReplicas
+
+	comparator := compare.DefaultComparator()
+	assert.False(t, comparator.Compare(deployedDeployment, &requestedDeployment), "Expected replica change to be detected without autoscaled targets")
+	assert.False(t, comparator.Compare(deployedStatefulSet, &requestedStatefulSet), "Expected replica change to be detected without autoscaled targets")
+
+	comparator.SetAutoscaledTargets(compare.GetAutoscaledTargets([]resource.KubernetesResource{
+		&autoscalingv1.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "autoscaler", Namespace: "namespace"},
+			Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "workload"},
+			},
+		},
+	}))
+	assert.True(t, comparator.Compare(deployedDeployment, &requestedDeployment), "Expected replicas of autoscaled deployment to be ignored")
+	assert.False(t, comparator.Compare(deployedStatefulSet, &requestedStatefulSet), "Expected statefulset of the same name to be compared, since it is not the scale target")
+	assert.Equal(t, int32(2), *requestedDeployment.Spec.Replicas, "Expected requested object to be left unchanged")
+
+	comparator.SetAutoscaledTargets(compare.AutoscaledTargets{
+		{GroupVersionKind: appsv1.SchemeGroupVersion.WithKind("StatefulSet"), Namespace: "namespace", Name: "workload"}: true,
+	})
+	assert.True(t, comparator.Compare(deployedStatefulSet, &requestedStatefulSet), "Expected replicas of autoscaled statefulset to be ignored")
+
+	comparator.SetAutoscaledTargets(compare.AutoscaledTargets{
+		{GroupVersionKind: extensionsv1beta1.SchemeGroupVersion.WithKind("Deployment"), Namespace: "namespace", Name: "workload"}: true,
+	})
+	assert.False(t, comparator.Compare(deployedDeployment, &requestedDeployment), "Expected an autoscaler targeting the extensions/v1beta1 api to be ignored")
+}
+
+func TestCompareArraysWithDiff(t *testing.T) {
+	svcs := test.GetServices(2)
+	svcs[1].Name = svcs[0].Name
+	svcs[1].Spec.SessionAffinity = corev1.ServiceAffinityClientIP
+
+	delta := compare.DefaultComparator().CompareArrays([]resource.KubernetesResource{&svcs[0]}, []resource.KubernetesResource{&svcs[1]})
+	assert.Len(t, delta.Updated, 1, "Expected 1 updated service")
+	diffs := delta.Diffs[delta.Updated[0]]
+	assert.Len(t, diffs, 1, "Expected a single difference to be reported")
+	assert.Equal(t, "spec.sessionAffinity", diffs[0].Path)
+	assert.Equal(t, corev1.ServiceAffinity(""), diffs[0].Deployed)
+	assert.Equal(t, corev1.ServiceAffinityClientIP, diffs[0].Requested)
+}
+
+func TestCompareWithDiffCustomComparator(t *testing.T) {
+	svcs := test.GetServices(2)
+	svcs[1].Spec.ClusterIP = "127.0.0.1"
+	comparator := compare.DefaultComparator()
+	comparator.SetComparator(reflect.TypeOf(corev1.Service{}), func(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+		return false
+	})
+
+	equal, diffs := comparator.CompareWithDiff(&svcs[0], &svcs[1])
+	assert.False(t, equal, "Expected custom comparator to be used")
+	assert.Len(t, diffs, 2, "Expected generic diff of name and cluster IP")
+	assert.Equal(t, "metadata.name", diffs[0].Path)
+	assert.Equal(t, "spec.clusterIP", diffs[1].Path)
+}
+
+func TestCompareArraysAcrossNamespaces(t *testing.T) {
+	svcs := test.GetServices(4)
+	for index := range svcs {
+		svcs[index].Name = "service"
+		svcs[index].Namespace = fmt.Sprintf("namespace%d", index%2+1)
+	}
+	svcs[3].Spec.ClusterIP = "127.0.0.1"
+
+	comparator := compare.DefaultComparator()
+	delta := comparator.CompareArrays([]resource.KubernetesResource{&svcs[0], &svcs[1]}, []resource.KubernetesResource{&svcs[2], &svcs[3]})
+	assert.Empty(t, delta.Added, "Expected no added services")
+	assert.Empty(t, delta.Removed, "Expected no removed services")
+	assert.Len(t, delta.Updated, 1, "Expected 1 updated service")
+	assert.Equal(t, "namespace2", delta.Updated[0].GetNamespace(), "Expected the service in namespace2 to be updated")
+
+	comparator.SetKeyFunc(func(object resource.KubernetesResource) string {
+		return object.GetName()
+	})
+	delta = comparator.CompareArrays([]resource.KubernetesResource{&svcs[0]}, []resource.KubernetesResource{&svcs[3]})
+	assert.Empty(t, delta.Added, "Expected custom key function to ignore namespace")
+	assert.Len(t, delta.Updated, 1, "Expected 1 updated service")
+
+	comparator.SetKeyFunc(nil)
+	assert.NotNil(t, comparator.GetKeyFunc(), "Expected a nil key function to restore the default")
+	delta = comparator.CompareArrays([]resource.KubernetesResource{&svcs[0]}, []resource.KubernetesResource{&svcs[3]})
+	assert.Len(t, delta.Added, 1, "Expected the default key function to tell namespaces apart")
+	assert.Len(t, delta.Removed, 1, "Expected the default key function to tell namespaces apart")
+}
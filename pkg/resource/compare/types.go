@@ -9,6 +9,8 @@ type ResourceDelta struct {
 	Added   []resource.KubernetesResource
 	Updated []resource.KubernetesResource
 	Removed []resource.KubernetesResource
+	// Diffs holds the differences found between each updated resource and its deployed counterpart
+	Diffs map[resource.KubernetesResource][]Difference
 }
 
 func (delta *ResourceDelta) HasChanges() bool {
@@ -29,20 +31,35 @@ type ResourceComparator interface {
 	GetDefaultComparator() func(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool
 	SetComparator(resourceType reflect.Type, compFunc func(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool)
 	GetComparator(resourceType reflect.Type) func(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool
+	SetKeyFunc(keyFunc func(object resource.KubernetesResource) string)
+	GetKeyFunc() func(object resource.KubernetesResource) string
 	Compare(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool
+	CompareWithDiff(deployed resource.KubernetesResource, requested resource.KubernetesResource) (bool, []Difference)
 	CompareArrays(deployed []resource.KubernetesResource, requested []resource.KubernetesResource) ResourceDelta
+	SetAutoscaledTargets(targets AutoscaledTargets)
+	GetAutoscaledTargets() AutoscaledTargets
 }
 
 func DefaultComparator() ResourceComparator {
-	return &resourceComparator{
-		deepEquals,
-		defaultMap(),
+	comparator := &resourceComparator{
+		defaultCompareFunc: equalSemantic,
+		defaultDiffFunc:    diffSemantic,
+		keyFunc:            NamespacedKey,
 	}
+	//The workload comparators look up the autoscaled targets of this comparator each time they are called
+	comparator.compareFuncMap = defaultMap(&comparator.autoscaledTargets)
+	comparator.diffFuncMap = defaultDiffMap(&comparator.autoscaledTargets)
+	return comparator
 }
 
+// SimpleComparator creates a comparator with no type-specific logic, which compares the Spec of resources by value
+// unlike the default comparator, fields left unset in the requested resource are compared as well, and autoscaled targets are not ignored
 func SimpleComparator() ResourceComparator {
 	return &resourceComparator{
-		deepEquals,
-		make(map[reflect.Type]func(resource.KubernetesResource, resource.KubernetesResource) bool),
+		defaultCompareFunc: deepEquals,
+		defaultDiffFunc:    deepDiff,
+		compareFuncMap:     make(map[reflect.Type]func(resource.KubernetesResource, resource.KubernetesResource) bool),
+		diffFuncMap:        make(map[reflect.Type]func(resource.KubernetesResource, resource.KubernetesResource) []Difference),
+		keyFunc:            NamespacedKey,
 	}
 }
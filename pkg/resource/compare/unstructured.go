@@ -0,0 +1,38 @@
+package compare
+
+import (
+	"github.com/RHsyseng/operator-utils/pkg/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"reflect"
+)
+
+// Fields of an unstructured object that are populated and managed by the server
+var serverManagedFields = [][]string{
+	{"status"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "uid"},
+	{"metadata", "generation"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "deletionTimestamp"},
+	{"metadata", "selfLink"},
+	{"metadata", "managedFields"},
+}
+
+func equalUnstructured(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+	return len(diffUnstructured(deployed, requested)) == 0
+}
+
+// diffUnstructured compares unstructured resources like diffSemantic compares typed ones
+// server-managed fields are ignored, and fields that are missing from the requested object are not compared
+func diffUnstructured(deployed resource.KubernetesResource, requested resource.KubernetesResource) []Difference {
+	u1 := deployed.(*unstructured.Unstructured)
+	u2 := requested.(*unstructured.Unstructured).DeepCopy()
+	for _, fields := range serverManagedFields {
+		unstructured.RemoveNestedField(u2.Object, fields...)
+	}
+	diffs := appendSubsetDiffs(nil, "", reflect.ValueOf(u1.Object), reflect.ValueOf(u2.Object))
+	if len(diffs) > 0 {
+		logger.Info("Resources are not equal", "kind", u1.GetKind(), "namespace", u1.GetNamespace(), "name", u1.GetName(), "differences", diffs)
+	}
+	return diffs
+}
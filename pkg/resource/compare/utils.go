@@ -2,27 +2,45 @@ package compare
 
 import (
 	"github.com/RHsyseng/operator-utils/pkg/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"reflect"
 )
 
 type mapBuilder struct {
-	resourceMap map[reflect.Type][]resource.KubernetesResource
+	resourceMap     map[reflect.Type][]resource.KubernetesResource
+	unstructuredMap map[schema.GroupVersionKind][]resource.KubernetesResource
 }
 
 func NewMapBuilder() *mapBuilder {
-	this := &mapBuilder{resourceMap: make(map[reflect.Type][]resource.KubernetesResource)}
+	this := &mapBuilder{
+		resourceMap:     make(map[reflect.Type][]resource.KubernetesResource),
+		unstructuredMap: make(map[schema.GroupVersionKind][]resource.KubernetesResource),
+	}
 	return this
 }
 
+// ResourceMap returns the typed resources that have been added, organized by type
 func (this *mapBuilder) ResourceMap() map[reflect.Type][]resource.KubernetesResource {
 	return this.resourceMap
 }
 
+// UnstructuredMap returns the unstructured resources that have been added, organized by GroupVersionKind
+func (this *mapBuilder) UnstructuredMap() map[schema.GroupVersionKind][]resource.KubernetesResource {
+	return this.unstructuredMap
+}
+
 func (this *mapBuilder) Add(resources ...resource.KubernetesResource) *mapBuilder {
 	for index := range resources {
 		if resources[index] == nil || reflect.ValueOf(resources[index]).IsNil() {
 			continue
 		}
+		if object, ok := resources[index].(*unstructured.Unstructured); ok {
+			//All unstructured objects share a Go type, so they are told apart by their kind
+			gvk := object.GroupVersionKind()
+			this.unstructuredMap[gvk] = append(this.unstructuredMap[gvk], resources[index])
+			continue
+		}
 		resourceType := reflect.ValueOf(resources[index]).Elem().Type()
 		this.resourceMap[resourceType] = append(this.resourceMap[resourceType], resources[index])
 	}
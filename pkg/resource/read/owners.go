@@ -0,0 +1,135 @@
+package read
+
+import (
+	"fmt"
+	"github.com/RHsyseng/operator-utils/pkg/resource"
+	newerror "github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"strings"
+)
+
+// OwnershipGraph holds the resources found under an owner, directly or through other owned resources
+// Children maps the UID of each owner in the tree to the resources it owns
+type OwnershipGraph struct {
+	Root     metav1.Object
+	Children map[types.UID][]resource.KubernetesResource
+}
+
+// ChildrenOf returns the resources directly owned by the provided owner, if it is part of the graph
+func (this *OwnershipGraph) ChildrenOf(owner metav1.Object) []resource.KubernetesResource {
+	return this.Children[owner.GetUID()]
+}
+
+// Descendants returns all resources in the graph, starting with the children of the root and going down one level at a time
+func (this *OwnershipGraph) Descendants() []resource.KubernetesResource {
+	var descendants []resource.KubernetesResource
+	owners := []types.UID{this.Root.GetUID()}
+	for len(owners) > 0 {
+		var next []types.UID
+		for _, owner := range owners {
+			for _, child := range this.Children[owner] {
+				descendants = append(descendants, child)
+				next = append(next, child.GetUID())
+			}
+		}
+		owners = next
+	}
+	return descendants
+}
+
+// ListOwnershipGraph lists resources based on provided List objects and configuration, and returns those owned by the configured owner object,
+// either directly or through other resources in the lists, such as the pods owned by replica sets of an owned deployment
+// persistent volume claims created from the claim templates of a stateful set are considered owned by it, even though they have no owner reference
+// only resources of the provided types are found, so types of intermediate owners need to be listed as well
+// label and field selectors of the reader are not applied, since owned resources, such as pods and replica sets, rarely carry the labels of their owners
+func (this *resourceReader) ListOwnershipGraph(listObjects ...runtime.Object) (*OwnershipGraph, error) {
+	if this.ownerObject == nil {
+		return nil, newerror.New("An owner object is required to list an ownership graph")
+	}
+	unfiltered := *this
+	unfiltered.ownerObject = nil
+	unfiltered.labelSelector = nil
+	unfiltered.fieldSelector = nil
+	childMap := make(map[types.UID][]resource.KubernetesResource)
+	var statefulSets []*appsv1.StatefulSet
+	var claims []*corev1.PersistentVolumeClaim
+	for _, listObject := range listObjects {
+		err := unfiltered.ListEach(listObject, func(item resource.KubernetesResource) error {
+			for _, ownerRef := range item.GetOwnerReferences() {
+				childMap[ownerRef.UID] = append(childMap[ownerRef.UID], item)
+			}
+			switch typed := item.(type) {
+			case *appsv1.StatefulSet:
+				statefulSets = append(statefulSets, typed)
+			case *corev1.PersistentVolumeClaim:
+				claims = append(claims, typed)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, statefulSet := range statefulSets {
+		for _, claim := range claims {
+			if isTemplateClaim(statefulSet, claim) && !isOwner(statefulSet, claim) {
+				childMap[statefulSet.UID] = append(childMap[statefulSet.UID], claim)
+			}
+		}
+	}
+
+	graph := &OwnershipGraph{
+		Root:     this.ownerObject,
+		Children: make(map[types.UID][]resource.KubernetesResource),
+	}
+	//Guard against ownership cycles, and resources reachable through more than one owner
+	visited := map[types.UID]bool{this.ownerObject.GetUID(): true}
+	owners := []types.UID{this.ownerObject.GetUID()}
+	for len(owners) > 0 {
+		var next []types.UID
+		for _, owner := range owners {
+			for _, child := range childMap[owner] {
+				if visited[child.GetUID()] {
+					continue
+				}
+				visited[child.GetUID()] = true
+				graph.Children[owner] = append(graph.Children[owner], child)
+				next = append(next, child.GetUID())
+			}
+		}
+		owners = next
+	}
+	return graph, nil
+}
+
+func isTemplateClaim(statefulSet *appsv1.StatefulSet, claim *corev1.PersistentVolumeClaim) bool {
+	if claim.Namespace != statefulSet.Namespace {
+		return false
+	}
+	for _, template := range statefulSet.Spec.VolumeClaimTemplates {
+		//Claims are named after the template, the stateful set and the ordinal of the pod
+		prefix := fmt.Sprintf("%s-%s-", template.Name, statefulSet.Name)
+		if strings.HasPrefix(claim.Name, prefix) {
+			if isOrdinal(strings.TrimPrefix(claim.Name, prefix)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isOrdinal(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, char := range value {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package read
+
+import (
+	"context"
+	"github.com/RHsyseng/operator-utils/pkg/resource"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestListOwnershipGraph(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, corev1.SchemeBuilder.AddToScheme(scheme), "Expect no errors building scheme")
+	assert.Nil(t, appsv1.SchemeBuilder.AddToScheme(scheme), "Expect no errors building scheme")
+	client := fake.NewFakeClientWithScheme(scheme)
+	owner := &corev1.ConfigMap{ObjectMeta: getObjectMeta("owner", "owner-uid")}
+
+	deployment := &appsv1.Deployment{ObjectMeta: getObjectMeta("deployment", "deployment-uid", owner.UID)}
+	replicaSet := &appsv1.ReplicaSet{ObjectMeta: getObjectMeta("deployment-1234", "replicaset-uid", deployment.UID)}
+	pod := &corev1.Pod{ObjectMeta: getObjectMeta("deployment-1234-abcd", "pod-uid", replicaSet.UID)}
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: getObjectMeta("statefulset", "statefulset-uid", owner.UID),
+		Spec: appsv1.StatefulSetSpec{
+			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{{ObjectMeta: v1.ObjectMeta{Name: "data"}}},
+		},
+	}
+	claim := &corev1.PersistentVolumeClaim{ObjectMeta: getObjectMeta("data-statefulset-0", "claim-uid")}
+	unrelatedClaim := &corev1.PersistentVolumeClaim{ObjectMeta: getObjectMeta("data-statefulset-backup", "unrelated-claim-uid")}
+	signedClaim := &corev1.PersistentVolumeClaim{ObjectMeta: getObjectMeta("data-statefulset-+1", "signed-claim-uid")}
+	//Claims of another stateful set whose name starts with the same prefix
+	otherStatefulSetClaim := &corev1.PersistentVolumeClaim{ObjectMeta: getObjectMeta("data-statefulset-extra-0", "other-claim-uid")}
+	unrelatedPod := &corev1.Pod{ObjectMeta: getObjectMeta("unrelated", "unrelated-uid", "other-uid")}
+	for _, object := range []runtime.Object{deployment, replicaSet, pod, statefulSet, claim, unrelatedClaim, signedClaim, otherStatefulSetClaim, unrelatedPod} {
+		assert.Nil(t, client.Create(context.TODO(), object), "Expect no errors mock creating objects")
+	}
+
+	graph, err := New(client).WithNamespace(namespace).WithOwnerObject(owner).ListOwnershipGraph(&appsv1.DeploymentList{}, &appsv1.ReplicaSetList{}, &appsv1.StatefulSetList{}, &corev1.PodList{}, &corev1.PersistentVolumeClaimList{})
+	assert.Nil(t, err, "Expect no errors listing ownership graph")
+	assert.ElementsMatch(t, []string{"deployment", "statefulset"}, getNames(graph.ChildrenOf(owner)), "Expect direct children of the owner")
+	assert.Equal(t, []string{"deployment-1234"}, getNames(graph.ChildrenOf(deployment)), "Expect replica set to be owned by deployment")
+	assert.Equal(t, []string{"deployment-1234-abcd"}, getNames(graph.ChildrenOf(replicaSet)), "Expect pod to be owned by replica set")
+	assert.Equal(t, []string{"data-statefulset-0"}, getNames(graph.ChildrenOf(statefulSet)), "Expect claim from template to be owned by stateful set")
+	assert.Len(t, graph.Descendants(), 5, "Expect all owned resources and none of the unrelated ones")
+
+	_, err = New(client).WithNamespace(namespace).ListOwnershipGraph(&corev1.PodList{})
+	assert.NotNil(t, err, "Expect an error without an owner object")
+}
+
+func TestListOwnershipGraphIgnoresSelectors(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, corev1.SchemeBuilder.AddToScheme(scheme), "Expect no errors building scheme")
+	assert.Nil(t, appsv1.SchemeBuilder.AddToScheme(scheme), "Expect no errors building scheme")
+	client := fake.NewFakeClientWithScheme(scheme)
+	owner := &corev1.ConfigMap{ObjectMeta: getObjectMeta("owner", "owner-uid")}
+
+	deployment := &appsv1.Deployment{ObjectMeta: getObjectMeta("deployment", "deployment-uid", owner.UID)}
+	deployment.Labels = map[string]string{"app": "owner"}
+	//Neither the replica set nor the pod carries the label of the deployment
+	replicaSet := &appsv1.ReplicaSet{ObjectMeta: getObjectMeta("deployment-1234", "replicaset-uid", deployment.UID)}
+	pod := &corev1.Pod{ObjectMeta: getObjectMeta("deployment-1234-abcd", "pod-uid", replicaSet.UID)}
+	for _, object := range []runtime.Object{deployment, replicaSet, pod} {
+		assert.Nil(t, client.Create(context.TODO(), object), "Expect no errors mock creating objects")
+	}
+
+	reader := New(client).WithNamespace(namespace).WithOwnerObject(owner).WithMatchingLabels(map[string]string{"app": "owner"})
+	graph, err := reader.ListOwnershipGraph(&appsv1.DeploymentList{}, &appsv1.ReplicaSetList{}, &corev1.PodList{})
+	assert.Nil(t, err, "Expect no errors listing ownership graph")
+	assert.Equal(t, []string{"deployment-1234"}, getNames(graph.ChildrenOf(deployment)), "Expect unlabelled replica set to be owned by deployment")
+	assert.Equal(t, []string{"deployment-1234-abcd"}, getNames(graph.ChildrenOf(replicaSet)), "Expect unlabelled pod to be owned by replica set")
+	assert.Len(t, graph.Descendants(), 3, "Expect all owned resources regardless of their labels")
+}
+
+func getObjectMeta(name string, uid types.UID, ownerUIDs ...types.UID) v1.ObjectMeta {
+	objectMeta := v1.ObjectMeta{
+		Name:      name,
+		Namespace: namespace,
+		UID:       uid,
+	}
+	for _, ownerUID := range ownerUIDs {
+		objectMeta.OwnerReferences = append(objectMeta.OwnerReferences, v1.OwnerReference{UID: ownerUID})
+	}
+	return objectMeta
+}
+
+func getNames(resources []resource.KubernetesResource) []string {
+	var names []string
+	for _, res := range resources {
+		names = append(names, res.GetName())
+	}
+	return names
+}
@@ -2,24 +2,74 @@ package read
 
 import (
 	"context"
+	"fmt"
 	"github.com/RHsyseng/operator-utils/pkg/resource"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"reflect"
 	clientv1 "sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 type resourceReader struct {
-	reader      clientv1.Reader
-	namespace   string
-	ownerObject metav1.Object
+	reader         clientv1.Reader
+	ctx            context.Context
+	namespace      string
+	ownerObject    metav1.Object
+	labelSelector  labels.Selector
+	fieldSelector  fields.Selector
+	pageSize       int64
+	parallelism    int
+	partialResults bool
+}
+
+// ListError reports the list types that failed to be listed, with the error for each of them, organized by item type
+type ListError struct {
+	Errors map[reflect.Type]error
+}
+
+func (this *ListError) Error() string {
+	var messages []string
+	for itemType, err := range this.Errors {
+		messages = append(messages, fmt.Sprintf("%s: %v", itemType.Name(), err))
+	}
+	sort.Strings(messages)
+	return fmt.Sprintf("Failed to list resources: %s", strings.Join(messages, ", "))
+}
+
+// UnstructuredListError is the equivalent of ListError for unstructured resources, organized by GroupVersionKind
+type UnstructuredListError struct {
+	Errors map[schema.GroupVersionKind]error
+}
+
+func (this *UnstructuredListError) Error() string {
+	var messages []string
+	for gvk, err := range this.Errors {
+		messages = append(messages, fmt.Sprintf("%s: %v", gvk.String(), err))
+	}
+	sort.Strings(messages)
+	return fmt.Sprintf("Failed to list resources: %s", strings.Join(messages, ", "))
 }
 
 // New creates a resourceReader object that can be used to load/list kubernetes resources
 // the provided reader object will be used for the underlying operations
 func New(reader clientv1.Reader) *resourceReader {
-	return &resourceReader{reader: reader}
+	return &resourceReader{reader: reader, ctx: context.TODO()}
+}
+
+// WithContext sets the context passed to underlying calls, so that their deadline and cancellation are honored
+func (this *resourceReader) WithContext(ctx context.Context) *resourceReader {
+	this.ctx = ctx
+	return this
 }
 
 // WithNamespace filters list operations to the provided namespace
@@ -34,22 +84,119 @@ func (this *resourceReader) WithOwnerObject(ownerObject metav1.Object) *resource
 	return this
 }
 
+// WithLabelSelector filters list operations to items matching the provided label selector
+// the selector is sent with the list request, so filtering happens on the server or in the cache
+// calling it again, or along with WithMatchingLabels, requires items to match all provided selectors
+func (this *resourceReader) WithLabelSelector(selector labels.Selector) *resourceReader {
+	if this.labelSelector == nil {
+		this.labelSelector = selector
+	} else if requirements, selectable := selector.Requirements(); selectable {
+		this.labelSelector = this.labelSelector.Add(requirements...)
+	} else {
+		this.labelSelector = labels.Nothing()
+	}
+	return this
+}
+
+// WithMatchingLabels filters list operations to items that have all the provided labels and values
+func (this *resourceReader) WithMatchingLabels(matchingLabels map[string]string) *resourceReader {
+	return this.WithLabelSelector(labels.SelectorFromSet(matchingLabels))
+}
+
+// WithFieldSelector filters list operations to items matching the provided field selector, such as metadata.name=value
+// the fields that can be selected depend on the resource type, and calling it again requires items to match all provided selectors
+func (this *resourceReader) WithFieldSelector(selector fields.Selector) *resourceReader {
+	if this.fieldSelector == nil {
+		this.fieldSelector = selector
+	} else {
+		this.fieldSelector = fields.AndSelectors(this.fieldSelector, selector)
+	}
+	return this
+}
+
+// WithPageSize makes list operations fetch results in pages of at most pageSize items, using the continue token of each page to fetch the next one
+// this avoids loading very large lists in a single call, but requires a reader that talks to the API server, as the cache does not paginate
+func (this *resourceReader) WithPageSize(pageSize int64) *resourceReader {
+	this.pageSize = pageSize
+	return this
+}
+
+// WithParallelism makes ListAll and ListAllUnstructured run up to the provided number of list calls concurrently
+// by default, list calls are made one at a time
+func (this *resourceReader) WithParallelism(parallelism int) *resourceReader {
+	this.parallelism = parallelism
+	return this
+}
+
+// WithPartialResults makes ListAll and ListAllUnstructured return the resources of the types that could be listed, even when others fail,
+// such as types whose API is not available on the cluster, in which case the returned error reports each failure,
+// as a ListError for ListAll and an UnstructuredListError for ListAllUnstructured
+func (this *resourceReader) WithPartialResults() *resourceReader {
+	this.partialResults = true
+	return this
+}
+
+func (this *resourceReader) listOptions() *clientv1.ListOptions {
+	return &clientv1.ListOptions{
+		Namespace:     this.namespace,
+		LabelSelector: this.labelSelector,
+		FieldSelector: this.fieldSelector,
+	}
+}
+
 // List returns a list of Kubernetes resources based on provided List object and configuration
 // any error from underlying calls is directly returned as well
 func (this *resourceReader) List(listObject runtime.Object) ([]resource.KubernetesResource, error) {
 	var resources []resource.KubernetesResource
-	err := this.reader.List(context.TODO(), &clientv1.ListOptions{Namespace: this.namespace}, listObject)
+	err := this.ListEach(listObject, func(item resource.KubernetesResource) error {
+		resources = append(resources, item)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	itemsValue := reflect.Indirect(reflect.ValueOf(listObject)).FieldByName("Items")
-	for index := 0; index < itemsValue.Len(); index++ {
-		item := addr(itemsValue.Index(index)).Interface().(resource.KubernetesResource)
-		if this.ownerObject == nil || isOwner(this.ownerObject, item) {
-			resources = append(resources, item)
+	return resources, nil
+}
+
+// ListEach calls the provided function with each Kubernetes resource found based on provided List object and configuration
+// when a page size is configured, items are passed on as each page is loaded, and the provided List object only holds the first page
+// listing stops at the first error returned by the function or from underlying calls, and that error is directly returned
+func (this *resourceReader) ListEach(listObject runtime.Object, callback func(resource.KubernetesResource) error) error {
+	options := this.listOptions()
+	if this.pageSize > 0 {
+		options.Raw = &metav1.ListOptions{Limit: this.pageSize}
+	}
+	page := listObject
+	for {
+		err := this.reader.List(this.ctx, options, page)
+		if err != nil {
+			return err
+		}
+		itemsValue := reflect.Indirect(reflect.ValueOf(page)).FieldByName("Items")
+		for index := 0; index < itemsValue.Len(); index++ {
+			item := addr(itemsValue.Index(index)).Interface().(resource.KubernetesResource)
+			if this.ownerObject == nil || isOwner(this.ownerObject, item) {
+				err = callback(item)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if this.pageSize <= 0 {
+			return nil
+		}
+		listMeta, err := meta.ListAccessor(page)
+		if err != nil {
+			return err
+		}
+		if listMeta.GetContinue() == "" {
+			return nil
 		}
+		options.Raw.Continue = listMeta.GetContinue()
+		//Items passed on from a previous page must not be overwritten, so each page is loaded into a new list
+		page = reflect.New(reflect.ValueOf(listObject).Elem().Type()).Interface().(runtime.Object)
+		page.GetObjectKind().SetGroupVersionKind(listObject.GetObjectKind().GroupVersionKind())
 	}
-	return resources, nil
 }
 
 func addr(v reflect.Value) reflect.Value {
@@ -70,18 +217,117 @@ func isOwner(owner metav1.Object, res resource.KubernetesResource) bool {
 }
 
 // ListAll returns a map of Kubernetes resources organized by type, based on provided List objects and configuration
-// any error from underlying calls is directly returned as well
+// unless configured to return partial results, the error from the first failed list type is directly returned, and no other types are listed after it
 func (this *resourceReader) ListAll(listObjects ...runtime.Object) (map[reflect.Type][]resource.KubernetesResource, error) {
+	results := make([][]resource.KubernetesResource, len(listObjects))
+	errs := this.forEach(len(listObjects), func(index int) error {
+		var err error
+		results[index], err = this.List(listObjects[index])
+		return err
+	})
 	objectMap := make(map[reflect.Type][]resource.KubernetesResource)
-	for _, listObject := range listObjects {
-		resources, err := this.List(listObject)
-		if err != nil {
-			return nil, err
+	listErr := &ListError{Errors: make(map[reflect.Type]error)}
+	for index, listObject := range listObjects {
+		if errs[index] != nil {
+			if !this.partialResults {
+				return nil, errs[index]
+			}
+			listErr.Errors[itemType(listObject)] = errs[index]
+			continue
+		}
+		if len(results[index]) > 0 {
+			itemType := reflect.ValueOf(results[index][0]).Elem().Type()
+			objectMap[itemType] = results[index]
+		}
+	}
+	if len(listErr.Errors) > 0 {
+		return objectMap, listErr
+	}
+	return objectMap, nil
+}
+
+func itemType(listObject runtime.Object) reflect.Type {
+	itemsType := reflect.Indirect(reflect.ValueOf(listObject)).FieldByName("Items").Type().Elem()
+	if itemsType.Kind() == reflect.Ptr {
+		return itemsType.Elem()
+	}
+	return itemsType
+}
+
+// forEach calls the provided function for each index up to count, running as many calls concurrently as the configured parallelism allows
+// unless configured to return partial results, no further calls are started after one fails, and their errors are left nil
+func (this *resourceReader) forEach(count int, function func(index int) error) []error {
+	errs := make([]error, count)
+	parallelism := this.parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	semaphore := make(chan struct{}, parallelism)
+	var waitGroup sync.WaitGroup
+	var failed int32
+	for index := 0; index < count; index++ {
+		semaphore <- struct{}{}
+		if !this.partialResults && atomic.LoadInt32(&failed) > 0 {
+			<-semaphore
+			break
+		}
+		waitGroup.Add(1)
+		go func(index int) {
+			defer waitGroup.Done()
+			errs[index] = function(index)
+			if errs[index] != nil {
+				atomic.StoreInt32(&failed, 1)
+			}
+			<-semaphore
+		}(index)
+	}
+	waitGroup.Wait()
+	return errs
+}
+
+// ListUnstructured returns a list of Kubernetes resources of the provided GroupVersionKind, loaded as unstructured objects
+// this allows listing custom resources without their Go types, and is otherwise the same as List
+func (this *resourceReader) ListUnstructured(gvk schema.GroupVersionKind) ([]resource.KubernetesResource, error) {
+	listObject := &unstructured.UnstructuredList{}
+	listObject.SetGroupVersionKind(schema.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind + "List"})
+	resources, err := this.List(listObject)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range resources {
+		//Items of a list do not always carry their own kind
+		if item.GetObjectKind().GroupVersionKind().Empty() {
+			item.GetObjectKind().SetGroupVersionKind(gvk)
 		}
-		if len(resources) > 0 {
-			itemType := reflect.ValueOf(resources[0]).Elem().Type()
-			objectMap[itemType] = resources
+	}
+	return resources, nil
+}
+
+// ListAllUnstructured returns a map of unstructured Kubernetes resources organized by GroupVersionKind, based on provided kinds and configuration
+// unless configured to return partial results, the error from the first failed kind is directly returned, and no other kinds are listed after it
+func (this *resourceReader) ListAllUnstructured(gvks ...schema.GroupVersionKind) (map[schema.GroupVersionKind][]resource.KubernetesResource, error) {
+	results := make([][]resource.KubernetesResource, len(gvks))
+	errs := this.forEach(len(gvks), func(index int) error {
+		var err error
+		results[index], err = this.ListUnstructured(gvks[index])
+		return err
+	})
+	objectMap := make(map[schema.GroupVersionKind][]resource.KubernetesResource)
+	listErr := &UnstructuredListError{Errors: make(map[schema.GroupVersionKind]error)}
+	for index, gvk := range gvks {
+		if errs[index] != nil {
+			if !this.partialResults {
+				return nil, errs[index]
+			}
+			listErr.Errors[gvk] = errs[index]
+			continue
 		}
+		if len(results[index]) > 0 {
+			objectMap[gvk] = results[index]
+		}
+	}
+	if len(listErr.Errors) > 0 {
+		return objectMap, listErr
 	}
 	return objectMap, nil
 }
@@ -90,6 +336,6 @@ func (this *resourceReader) ListAll(listObjects ...runtime.Object) (map[reflect.
 // any error from the underlying call, including a not-found error, is directly returned as well
 func (this *resourceReader) Load(resourceType reflect.Type, name string) (resource.KubernetesResource, error) {
 	deployed := reflect.New(resourceType).Interface().(resource.KubernetesResource)
-	err := this.reader.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: this.namespace}, deployed)
+	err := this.reader.Get(this.ctx, types.NamespacedName{Name: name, Namespace: this.namespace}, deployed)
 	return deployed, err
 }
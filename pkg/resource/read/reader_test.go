@@ -3,14 +3,23 @@ package read
 import (
 	"context"
 	"fmt"
+	"github.com/RHsyseng/operator-utils/pkg/resource"
 	monv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"reflect"
+	clientv1 "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 )
 
 var namespace = "ns"
@@ -60,6 +69,258 @@ func TestListObjects(t *testing.T) {
 	assert.Equal(t, &expectedServiceMonitors[1], listedServiceMonitors[1])
 }
 
+func TestListSelectedObjects(t *testing.T) {
+	scheme := runtime.NewScheme()
+	err := corev1.SchemeBuilder.AddToScheme(scheme)
+	assert.Nil(t, err, "Expect no errors building scheme")
+	client := fake.NewFakeClientWithScheme(scheme)
+	services := getServices(3)
+	services[0].Labels = map[string]string{"app.kubernetes.io/managed-by": "my-operator", "tier": "frontend"}
+	services[1].Labels = map[string]string{"app.kubernetes.io/managed-by": "my-operator", "tier": "backend"}
+	for index := range services {
+		assert.Nil(t, client.Create(context.TODO(), &services[index]), "Expect no errors mock creating objects")
+	}
+
+	listed, err := New(client).WithNamespace(namespace).WithMatchingLabels(map[string]string{"app.kubernetes.io/managed-by": "my-operator"}).List(&corev1.ServiceList{})
+	assert.Nil(t, err, "Expect no errors listing objects")
+	assert.Len(t, listed, 2, "Expect to find 2 labelled services")
+
+	selector, err := labels.Parse("tier in (backend)")
+	assert.Nil(t, err, "Expect no errors parsing selector")
+	listed, err = New(client).WithNamespace(namespace).WithMatchingLabels(map[string]string{"app.kubernetes.io/managed-by": "my-operator"}).WithLabelSelector(selector).List(&corev1.ServiceList{})
+	assert.Nil(t, err, "Expect no errors listing objects")
+	assert.Len(t, listed, 1, "Expect to find 1 service matching both selectors")
+	assert.Equal(t, "service-2", listed[0].GetName())
+}
+
+func TestListOptions(t *testing.T) {
+	client := &listOptionsRecorder{}
+	_, err := New(client).WithNamespace(namespace).WithFieldSelector(fields.OneTermEqualSelector("metadata.name", "service-1")).WithFieldSelector(fields.OneTermEqualSelector("spec.type", "ClusterIP")).WithMatchingLabels(map[string]string{"app": "my-app"}).List(&corev1.ServiceList{})
+	assert.Nil(t, err, "Expect no errors listing objects")
+	assert.Equal(t, namespace, client.options.Namespace)
+	assert.Equal(t, "app=my-app", client.options.LabelSelector.String())
+	assert.Equal(t, "metadata.name=service-1,spec.type=ClusterIP", client.options.FieldSelector.String())
+
+	_, err = New(client).List(&corev1.ServiceList{})
+	assert.Nil(t, err, "Expect no errors listing objects")
+	assert.Nil(t, client.options.LabelSelector, "Expect no label selector by default")
+	assert.Nil(t, client.options.FieldSelector, "Expect no field selector by default")
+}
+
+type listOptionsRecorder struct {
+	clientv1.Reader
+	options *clientv1.ListOptions
+	ctx     context.Context
+}
+
+func (this *listOptionsRecorder) List(ctx context.Context, opts *clientv1.ListOptions, list runtime.Object) error {
+	this.options = opts
+	this.ctx = ctx
+	return nil
+}
+
+func TestListPages(t *testing.T) {
+	scheme := runtime.NewScheme()
+	err := corev1.SchemeBuilder.AddToScheme(scheme)
+	assert.Nil(t, err, "Expect no errors building scheme")
+	client := fake.NewFakeClientWithScheme(scheme)
+	services := getServices(5)
+	for index := range services {
+		assert.Nil(t, client.Create(context.TODO(), &services[index]), "Expect no errors mock creating objects")
+	}
+
+	pager := &pagingReader{Reader: client}
+	listed, err := New(pager).WithNamespace(namespace).WithPageSize(2).List(&corev1.ServiceList{})
+	assert.Nil(t, err, "Expect no errors listing objects")
+	assert.Len(t, pager.requests, 3, "Expect 5 services to be listed in 3 pages")
+	assert.Equal(t, int64(2), pager.requests[0].Limit, "Expect page size to be used as limit")
+	assert.Equal(t, "", pager.requests[0].Continue, "Expect first page to be requested without a continue token")
+	assert.Equal(t, "2", pager.requests[1].Continue, "Expect continue token of previous page to be used")
+	var names []string
+	for _, item := range listed {
+		names = append(names, item.GetName())
+	}
+	assert.ElementsMatch(t, []string{"service-1", "service-2", "service-3", "service-4", "service-5"}, names, "Expect items of earlier pages to be kept")
+
+	pager = &pagingReader{Reader: client}
+	var count int
+	stop := fmt.Errorf("stop")
+	err = New(pager).WithNamespace(namespace).WithPageSize(2).ListEach(&corev1.ServiceList{}, func(item resource.KubernetesResource) error {
+		count++
+		if count == 3 {
+			return stop
+		}
+		return nil
+	})
+	assert.Equal(t, stop, err, "Expect callback error to be returned")
+	assert.Equal(t, 3, count, "Expect listing to stop at the callback error")
+	assert.Len(t, pager.requests, 2, "Expect no further pages to be requested")
+
+	pager = &pagingReader{Reader: client}
+	listed, err = New(pager).WithNamespace(namespace).List(&corev1.ServiceList{})
+	assert.Nil(t, err, "Expect no errors listing objects")
+	assert.Len(t, listed, 5, "Expect all services to be listed in a single call")
+	assert.Len(t, pager.requests, 1, "Expect all services to be listed in a single call")
+}
+
+// The fake client does not paginate, so pages are cut from its full list, with the index of the next item as continue token
+type pagingReader struct {
+	clientv1.Reader
+	requests []v1.ListOptions
+}
+
+func (this *pagingReader) List(ctx context.Context, opts *clientv1.ListOptions, list runtime.Object) error {
+	err := this.Reader.List(ctx, &clientv1.ListOptions{Namespace: opts.Namespace}, list)
+	if err != nil {
+		return err
+	}
+	raw := opts.AsListOptions()
+	this.requests = append(this.requests, *raw)
+	serviceList := list.(*corev1.ServiceList)
+	start := 0
+	if raw.Continue != "" {
+		start, err = strconv.Atoi(raw.Continue)
+		if err != nil {
+			return err
+		}
+	}
+	end := len(serviceList.Items)
+	serviceList.Continue = ""
+	if raw.Limit > 0 && start+int(raw.Limit) < end {
+		end = start + int(raw.Limit)
+		serviceList.Continue = strconv.Itoa(end)
+	}
+	serviceList.Items = serviceList.Items[start:end]
+	return nil
+}
+
+func TestListAllConcurrently(t *testing.T) {
+	scheme := runtime.NewScheme()
+	err := corev1.SchemeBuilder.AddToScheme(scheme)
+	assert.Nil(t, err, "Expect no errors building scheme")
+	client := fake.NewFakeClientWithScheme(scheme)
+	services := getServices(2)
+	for index := range services {
+		assert.Nil(t, client.Create(context.TODO(), &services[index]), "Expect no errors mock creating objects")
+	}
+
+	counter := &concurrencyCounter{Reader: client}
+	listObjects := []runtime.Object{&corev1.ServiceList{}, &corev1.PodList{}, &corev1.ConfigMapList{}, &corev1.SecretList{}, &corev1.ServiceAccountList{}, &corev1.EndpointsList{}}
+	objectMap, err := New(counter).WithNamespace(namespace).WithParallelism(3).ListAll(listObjects...)
+	assert.Nil(t, err, "Expect no errors listing objects")
+	assert.Len(t, objectMap[reflect.TypeOf(corev1.Service{})], 2, "Expect to find 2 services")
+	assert.Equal(t, int32(len(listObjects)), counter.calls, "Expect each type to be listed")
+	assert.True(t, counter.maximum > 1, "Expect list calls to run concurrently")
+	assert.True(t, counter.maximum <= 3, "Expect no more list calls than the parallelism to run concurrently")
+
+	counter = &concurrencyCounter{Reader: client}
+	_, err = New(counter).WithNamespace(namespace).ListAll(listObjects...)
+	assert.Nil(t, err, "Expect no errors listing objects")
+	assert.Equal(t, int32(1), counter.maximum, "Expect list calls to run one at a time by default")
+}
+
+func TestListAllPartialResults(t *testing.T) {
+	scheme := runtime.NewScheme()
+	err := corev1.SchemeBuilder.AddToScheme(scheme)
+	assert.Nil(t, err, "Expect no errors building scheme")
+	client := fake.NewFakeClientWithScheme(scheme)
+	services := getServices(2)
+	for index := range services {
+		assert.Nil(t, client.Create(context.TODO(), &services[index]), "Expect no errors mock creating objects")
+	}
+
+	//Service monitors are not registered in the scheme, like an API that is not available on the cluster
+	objectMap, err := New(client).WithNamespace(namespace).ListAll(&monv1.ServiceMonitorList{}, &corev1.ServiceList{})
+	assert.NotNil(t, err, "Expect an error listing an unknown type")
+	assert.Nil(t, objectMap, "Expect no results without partial results")
+
+	objectMap, err = New(client).WithNamespace(namespace).WithParallelism(2).WithPartialResults().ListAll(&monv1.ServiceMonitorList{}, &corev1.ServiceList{})
+	listErr, ok := err.(*ListError)
+	assert.True(t, ok, "Expect a list error reporting the failed type")
+	assert.Len(t, listErr.Errors, 1, "Expect a single failed type")
+	assert.NotNil(t, listErr.Errors[reflect.TypeOf(monv1.ServiceMonitor{})], "Expect error to be reported for the item type")
+	assert.Len(t, objectMap[reflect.TypeOf(corev1.Service{})], 2, "Expect services to be listed despite the failure")
+}
+
+func TestListAllUnstructuredPartialResults(t *testing.T) {
+	serviceGVK := schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+	monitorGVK := schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "ServiceMonitor"}
+	reader := &unstructuredReader{items: map[string][]unstructured.Unstructured{"ServiceList": getUnstructuredServices(2)}}
+
+	objectMap, err := New(reader).WithNamespace(namespace).ListAllUnstructured(monitorGVK, serviceGVK)
+	assert.NotNil(t, err, "Expect an error listing an unavailable kind")
+	assert.Nil(t, objectMap, "Expect no results without partial results")
+
+	objectMap, err = New(reader).WithNamespace(namespace).WithParallelism(2).WithPartialResults().ListAllUnstructured(monitorGVK, serviceGVK)
+	listErr, ok := err.(*UnstructuredListError)
+	assert.True(t, ok, "Expect an unstructured list error reporting the failed kind")
+	assert.Len(t, listErr.Errors, 1, "Expect a single failed kind")
+	assert.NotNil(t, listErr.Errors[monitorGVK], "Expect error to be reported for the kind")
+	assert.Len(t, objectMap[serviceGVK], 2, "Expect services to be listed despite the failure")
+}
+
+// unstructuredReader serves unstructured lists of the kinds it holds, and fails for any other kind, like an API that is not available on the cluster
+type unstructuredReader struct {
+	clientv1.Reader
+	items map[string][]unstructured.Unstructured
+}
+
+func (this *unstructuredReader) List(ctx context.Context, opts *clientv1.ListOptions, list runtime.Object) error {
+	unstructuredList := list.(*unstructured.UnstructuredList)
+	items, found := this.items[unstructuredList.GetKind()]
+	if !found {
+		return fmt.Errorf("no matches for kind %s", unstructuredList.GetKind())
+	}
+	unstructuredList.Items = items
+	return nil
+}
+
+func getUnstructuredServices(count int) []unstructured.Unstructured {
+	var services []unstructured.Unstructured
+	for index := 0; index < count; index++ {
+		service := unstructured.Unstructured{}
+		service.SetAPIVersion("v1")
+		service.SetKind("Service")
+		service.SetName(fmt.Sprintf("service%d", index))
+		service.SetNamespace(namespace)
+		services = append(services, service)
+	}
+	return services
+}
+
+type concurrencyCounter struct {
+	clientv1.Reader
+	calls   int32
+	running int32
+	maximum int32
+	lock    sync.Mutex
+}
+
+func (this *concurrencyCounter) List(ctx context.Context, opts *clientv1.ListOptions, list runtime.Object) error {
+	this.lock.Lock()
+	this.calls++
+	this.running++
+	if this.running > this.maximum {
+		this.maximum = this.running
+	}
+	this.lock.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	err := this.Reader.List(ctx, opts, list)
+	this.lock.Lock()
+	this.running--
+	this.lock.Unlock()
+	return err
+}
+
+func TestReaderContext(t *testing.T) {
+	client := &listOptionsRecorder{}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	_, err := New(client).WithContext(ctx).List(&corev1.ServiceList{})
+	assert.Nil(t, err, "Expect no errors listing objects")
+	assert.Equal(t, ctx, client.ctx, "Expected context to be passed to list calls")
+}
+
 func TestLoadObject(t *testing.T) {
 	scheme := runtime.NewScheme()
 	err := corev1.SchemeBuilder.AddToScheme(scheme)
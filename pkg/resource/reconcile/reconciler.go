@@ -0,0 +1,211 @@
+// Package reconcile combines the reader, comparator and writer to bring deployed resources in line with requested ones
+// it lives in its own package rather than in pkg/resource, since the read, compare and write packages all import pkg/resource
+package reconcile
+
+import (
+	"context"
+	"github.com/RHsyseng/operator-utils/pkg/resource"
+	"github.com/RHsyseng/operator-utils/pkg/resource/compare"
+	"github.com/RHsyseng/operator-utils/pkg/resource/read"
+	"github.com/RHsyseng/operator-utils/pkg/resource/write"
+	newerror "github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"reflect"
+	clientv1 "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type reconciler struct {
+	client          clientv1.Client
+	ctx             context.Context
+	scheme          *runtime.Scheme
+	namespace       string
+	ownerObject     metav1.Object
+	comparator      compare.MapComparator
+	listObjects     []runtime.Object
+	listKinds       []schema.GroupVersionKind
+	patchUpdates    bool
+	continueOnError bool
+}
+
+// Summary lists the resources that were found to be missing, different or no longer requested, and have been successfully written accordingly
+// resources that failed to be written, or were skipped after a failure, are not listed
+type Summary struct {
+	Added   []resource.KubernetesResource
+	Updated []resource.KubernetesResource
+	Removed []resource.KubernetesResource
+	Changed bool
+}
+
+// New creates a reconciler that reads, compares and writes kubernetes resources through the provided client
+// the scheme is used to infer list types for the requested resources, and to set the owner controller
+func New(client clientv1.Client, scheme *runtime.Scheme) *reconciler {
+	return &reconciler{
+		client:     client,
+		ctx:        context.TODO(),
+		scheme:     scheme,
+		comparator: compare.NewMapComparator(),
+	}
+}
+
+// WithContext sets the context passed to the reader, the writer and update hooks, so that their deadline and cancellation are honored
+func (this *reconciler) WithContext(ctx context.Context) *reconciler {
+	this.ctx = ctx
+	return this
+}
+
+// WithNamespace restricts listing deployed resources to the provided namespace
+func (this *reconciler) WithNamespace(namespace string) *reconciler {
+	this.namespace = namespace
+	return this
+}
+
+// WithOwnerObject only considers deployed resources owned by ownerObject, and sets it as the controller of written resources
+func (this *reconciler) WithOwnerObject(ownerObject metav1.Object) *reconciler {
+	this.ownerObject = ownerObject
+	return this
+}
+
+// WithComparator replaces the default map comparator used to compute the resource delta
+func (this *reconciler) WithComparator(comparator compare.MapComparator) *reconciler {
+	this.comparator = comparator
+	return this
+}
+
+// WithListTypes adds list types to read, in addition to those inferred from the requested resources
+// deployed resources of a type that is no longer requested are only removed if their list type is provided here
+func (this *reconciler) WithListTypes(listObjects ...runtime.Object) *reconciler {
+	this.listObjects = append(this.listObjects, listObjects...)
+	return this
+}
+
+// WithListKinds adds kinds to read as unstructured resources, in addition to the kinds of requested unstructured resources
+// deployed resources of a kind that is no longer requested are only removed if their kind is provided here
+func (this *reconciler) WithListKinds(gvks ...schema.GroupVersionKind) *reconciler {
+	this.listKinds = append(this.listKinds, gvks...)
+	return this
+}
+
+// WithPatchUpdates makes updates merge the requested fields into deployed resources, as described for the resource writer
+func (this *reconciler) WithPatchUpdates() *reconciler {
+	this.patchUpdates = true
+	return this
+}
+
+// WithContinueOnError makes the writer carry on with remaining resources after a failure, as described for the resource writer
+func (this *reconciler) WithContinueOnError() *reconciler {
+	this.continueOnError = true
+	return this
+}
+
+// Reconcile lists the deployed resources, compares them with the requested ones and applies the delta in dependency order
+// requested unstructured resources are read, compared and applied by GroupVersionKind, after typed resources
+// the returned summary describes the resources that were written, and is returned along with any error from the underlying calls
+func (this *reconciler) Reconcile(requested []resource.KubernetesResource) (Summary, error) {
+	summary := Summary{}
+	var typed, unstructuredResources []resource.KubernetesResource
+	for _, res := range requested {
+		if _, ok := res.(*unstructured.Unstructured); ok {
+			unstructuredResources = append(unstructuredResources, res)
+		} else {
+			typed = append(typed, res)
+		}
+	}
+	listObjects, err := this.inferListTypes(typed)
+	if err != nil {
+		return summary, err
+	}
+	reader := read.New(this.client).WithContext(this.ctx).WithNamespace(this.namespace)
+	if this.ownerObject != nil {
+		reader.WithOwnerObject(this.ownerObject)
+	}
+	deployed, err := reader.ListAll(listObjects...)
+	if err != nil {
+		return summary, err
+	}
+	deployedUnstructured, err := reader.ListAllUnstructured(this.inferListKinds(unstructuredResources)...)
+	if err != nil {
+		return summary, err
+	}
+	requestedMap := compare.NewMapBuilder().Add(requested...)
+	deltas := this.comparator.Compare(deployed, requestedMap.ResourceMap())
+	unstructuredDeltas := this.comparator.CompareUnstructured(deployedUnstructured, requestedMap.UnstructuredMap())
+
+	writer := write.New(this.client).WithContext(this.ctx)
+	if this.ownerObject != nil {
+		writer.WithOwnerController(this.ownerObject, this.scheme)
+	}
+	if this.patchUpdates {
+		writer.WithPatchUpdates()
+	}
+	if this.continueOnError {
+		writer.WithContinueOnError()
+	}
+	applier := write.NewApplier(writer)
+	report, err := applier.ApplyWithReport(deployed, deltas)
+	summary.add(report)
+	if err != nil && !this.continueOnError {
+		return summary, err
+	}
+	unstructuredReport, unstructuredErr := applier.ApplyUnstructuredWithReport(deployedUnstructured, unstructuredDeltas)
+	summary.add(unstructuredReport)
+	return summary, utilerrors.NewAggregate([]error{err, unstructuredErr})
+}
+
+func (this *Summary) add(report write.ApplyReport) {
+	this.Added = append(this.Added, succeededResources(report.Added)...)
+	this.Updated = append(this.Updated, succeededResources(report.Updated)...)
+	this.Removed = append(this.Removed, succeededResources(report.Removed)...)
+	this.Changed = this.Changed || report.HasChanges()
+}
+
+func succeededResources(report write.Report) []resource.KubernetesResource {
+	var resources []resource.KubernetesResource
+	for _, result := range report.Succeeded() {
+		resources = append(resources, result.Resource)
+	}
+	return resources
+}
+
+func (this *reconciler) inferListKinds(requested []resource.KubernetesResource) []schema.GroupVersionKind {
+	gvks := append([]schema.GroupVersionKind{}, this.listKinds...)
+	found := make(map[schema.GroupVersionKind]bool)
+	for _, gvk := range gvks {
+		found[gvk] = true
+	}
+	for _, res := range requested {
+		gvk := res.GetObjectKind().GroupVersionKind()
+		if !found[gvk] {
+			found[gvk] = true
+			gvks = append(gvks, gvk)
+		}
+	}
+	return gvks
+}
+
+func (this *reconciler) inferListTypes(requested []resource.KubernetesResource) ([]runtime.Object, error) {
+	listObjects := append([]runtime.Object{}, this.listObjects...)
+	listTypes := make(map[reflect.Type]bool)
+	for _, listObject := range listObjects {
+		listTypes[reflect.TypeOf(listObject)] = true
+	}
+	for _, res := range requested {
+		gvks, _, err := this.scheme.ObjectKinds(res)
+		if err != nil {
+			return nil, err
+		}
+		listGVK := schema.GroupVersionKind{Group: gvks[0].Group, Version: gvks[0].Version, Kind: gvks[0].Kind + "List"}
+		listObject, err := this.scheme.New(listGVK)
+		if err != nil {
+			return nil, newerror.Wrapf(err, "Failed to infer list type for %s", gvks[0].Kind)
+		}
+		if !listTypes[reflect.TypeOf(listObject)] {
+			listTypes[reflect.TypeOf(listObject)] = true
+			listObjects = append(listObjects, listObject)
+		}
+	}
+	return listObjects, nil
+}
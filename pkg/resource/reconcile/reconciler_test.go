@@ -0,0 +1,162 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"github.com/RHsyseng/operator-utils/pkg/resource"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientv1 "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestReconcile(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, corev1.SchemeBuilder.AddToScheme(scheme), "Expect no errors building scheme")
+	assert.Nil(t, appsv1.SchemeBuilder.AddToScheme(scheme), "Expect no errors building scheme")
+	client := fake.NewFakeClientWithScheme(scheme)
+	owner := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "owner",
+			Namespace: "namespace",
+			UID:       "owner-uid",
+		},
+	}
+
+	summary, err := New(client, scheme).WithNamespace("namespace").WithOwnerObject(owner).Reconcile(getRequested())
+	assert.Nil(t, err, "Expect no errors reconciling resources")
+	assert.True(t, summary.Changed, "Expected resources to be created")
+	assert.Len(t, summary.Added, 2, "Expected service and deployment to be added")
+
+	summary, err = New(client, scheme).WithNamespace("namespace").WithOwnerObject(owner).Reconcile(getRequested())
+	assert.Nil(t, err, "Expect no errors reconciling resources")
+	assert.False(t, summary.Changed, "Expected no changes for resources already deployed")
+
+	requested := getRequested()[:1]
+	summary, err = New(client, scheme).WithNamespace("namespace").WithOwnerObject(owner).WithListTypes(&appsv1.DeploymentList{}).Reconcile(requested)
+	assert.Nil(t, err, "Expect no errors reconciling resources")
+	assert.True(t, summary.Changed, "Expected deployment to be removed")
+	assert.Len(t, summary.Removed, 1, "Expected deployment to be removed")
+	assert.Equal(t, "deployment1", summary.Removed[0].GetName())
+
+	deployments := &appsv1.DeploymentList{}
+	assert.Nil(t, client.List(context.TODO(), &clientv1.ListOptions{Namespace: "namespace"}, deployments), "Expect no errors listing objects")
+	assert.Empty(t, deployments.Items, "Expected deployment to be removed")
+}
+
+func TestReconcileSummaryOfFailedWrites(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, corev1.SchemeBuilder.AddToScheme(scheme), "Expect no errors building scheme")
+	assert.Nil(t, appsv1.SchemeBuilder.AddToScheme(scheme), "Expect no errors building scheme")
+	client := &failingCreateClient{Client: fake.NewFakeClientWithScheme(scheme), failName: "service1"}
+
+	summary, err := New(client, scheme).WithNamespace("namespace").WithContinueOnError().Reconcile(getRequested())
+	assert.NotNil(t, err, "Expect the failed create to be reported")
+	assert.True(t, summary.Changed, "Expected deployment to be created")
+	assert.Len(t, summary.Added, 1, "Expected only the resource that was created to be listed")
+	assert.Equal(t, "deployment1", summary.Added[0].GetName())
+}
+
+type failingCreateClient struct {
+	clientv1.Client
+	failName string
+}
+
+func (this *failingCreateClient) Create(ctx context.Context, obj runtime.Object) error {
+	if obj.(resource.KubernetesResource).GetName() == this.failName {
+		return fmt.Errorf("failed to create %s", this.failName)
+	}
+	return this.Client.Create(ctx, obj)
+}
+
+func TestReconcileUnstructured(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "MyResource"}
+	scheme := runtime.NewScheme()
+	assert.Nil(t, corev1.SchemeBuilder.AddToScheme(scheme), "Expect no errors building scheme")
+	scheme.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind("MyResourceList"), &myResourceList{})
+	client := fake.NewFakeClientWithScheme(scheme)
+
+	summary, err := New(client, scheme).WithNamespace("namespace").Reconcile(getRequestedUnstructured(gvk, 1))
+	assert.Nil(t, err, "Expect no errors reconciling resources")
+	assert.True(t, summary.Changed, "Expected resources to be created")
+	assert.Len(t, summary.Added, 2, "Expected service and custom resource to be added")
+
+	summary, err = New(client, scheme).WithNamespace("namespace").Reconcile(getRequestedUnstructured(gvk, 1))
+	assert.Nil(t, err, "Expect no errors reconciling resources")
+	assert.False(t, summary.Changed, "Expected no changes for resources already deployed")
+
+	summary, err = New(client, scheme).WithNamespace("namespace").Reconcile(getRequestedUnstructured(gvk, 2))
+	assert.Nil(t, err, "Expect no errors reconciling resources")
+	assert.True(t, summary.Changed, "Expected custom resource to be updated")
+	assert.Len(t, summary.Updated, 1, "Expected custom resource to be updated")
+
+	deployed := &unstructured.Unstructured{}
+	deployed.SetGroupVersionKind(gvk)
+	assert.Nil(t, client.Get(context.TODO(), types.NamespacedName{Namespace: "namespace", Name: "resource1"}, deployed), "Expect no errors loading object")
+	size, _, _ := unstructured.NestedInt64(deployed.Object, "spec", "size")
+	assert.Equal(t, int64(2), size, "Expected custom resource to be updated")
+
+	summary, err = New(client, scheme).WithNamespace("namespace").WithListKinds(gvk).Reconcile(getRequestedUnstructured(gvk, 2)[:1])
+	assert.Nil(t, err, "Expect no errors reconciling resources")
+	assert.Len(t, summary.Removed, 1, "Expected custom resource to be removed")
+	assert.Equal(t, "resource1", summary.Removed[0].GetName())
+}
+
+// The fake client serializes lists built by its tracker, which leaves an unstructured list without the kind needed to decode it
+type myResourceList struct {
+	unstructured.UnstructuredList
+}
+
+func (this *myResourceList) DeepCopyObject() runtime.Object {
+	list := this.UnstructuredList.DeepCopy()
+	list.SetGroupVersionKind(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "MyResourceList"})
+	return list
+}
+
+func (this *myResourceList) MarshalJSON() ([]byte, error) {
+	return this.DeepCopyObject().(*unstructured.UnstructuredList).MarshalJSON()
+}
+
+func getRequestedUnstructured(gvk schema.GroupVersionKind, size int64) []resource.KubernetesResource {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "service1",
+			Namespace: "namespace",
+		},
+	}
+	custom := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"size": size,
+			},
+		},
+	}
+	custom.SetGroupVersionKind(gvk)
+	custom.SetName("resource1")
+	custom.SetNamespace("namespace")
+	return []resource.KubernetesResource{service, custom}
+}
+
+func getRequested() []resource.KubernetesResource {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "service1",
+			Namespace: "namespace",
+		},
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "deployment1",
+			Namespace: "namespace",
+		},
+	}
+	return []resource.KubernetesResource{service, deployment}
+}
@@ -86,6 +86,25 @@ func GetDeployments(count int) []appsv1.Deployment {
 	return slice
 }
 
+func GetStatefulSets(count int) []appsv1.StatefulSet {
+	var slice []appsv1.StatefulSet
+	for i := 0; i < count; i++ {
+		ss := appsv1.StatefulSet{
+			TypeMeta:   metav1.TypeMeta{},
+			ObjectMeta: metav1.ObjectMeta{},
+			Spec: appsv1.StatefulSetSpec{
+				Template: corev1.PodTemplateSpec{},
+			},
+			Status: appsv1.StatefulSetStatus{
+				ReadyReplicas: 0,
+			},
+		}
+		ss.Name = fmt.Sprintf("%s%d", "statefulset", i+1)
+		slice = append(slice, ss)
+	}
+	return slice
+}
+
 func GetSecrets(count int) []corev1.Secret {
 	var slice []corev1.Secret
 	for i := 0; i < count; i++ {
@@ -9,3 +9,14 @@ type KubernetesResource interface {
 	metav1.Object
 	runtime.Object
 }
+
+// LastAppliedAnnotation records the fields last requested for a resource written in patch mode,
+// so that fields removed from a later request can also be removed from the resource
+const LastAppliedAnnotation = "operator-utils.rhsyseng.github.io/last-applied-configuration"
+
+// ClaimBindAnnotations are set by the persistent volume controller when binding and provisioning claims
+var ClaimBindAnnotations = []string{
+	"pv.kubernetes.io/bind-completed",
+	"pv.kubernetes.io/bound-by-controller",
+	"volume.beta.kubernetes.io/storage-provisioner",
+}
@@ -0,0 +1,202 @@
+package write
+
+import (
+	"github.com/RHsyseng/operator-utils/pkg/resource"
+	"github.com/RHsyseng/operator-utils/pkg/resource/compare"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"reflect"
+	"sort"
+)
+
+// Ranks used to order the application of resource deltas, lower ranks are created and updated first and removed last
+const (
+	OrderNamespaces                = 10
+	OrderCustomResourceDefinitions = 20
+	OrderServiceAccounts           = 30
+	OrderRBAC                      = 40
+	OrderConfiguration             = 50
+	OrderServices                  = 60
+	OrderBuilds                    = 65
+	OrderWorkloads                 = 70
+	OrderRoutes                    = 80
+	OrderCustomResources           = 90
+)
+
+var defaultKindOrder = map[string]int{
+	"Namespace":                OrderNamespaces,
+	"CustomResourceDefinition": OrderCustomResourceDefinitions,
+	"ServiceAccount":           OrderServiceAccounts,
+	"Role":                     OrderRBAC,
+	"RoleBinding":              OrderRBAC,
+	"ClusterRole":              OrderRBAC,
+	"ClusterRoleBinding":       OrderRBAC,
+	"Secret":                   OrderConfiguration,
+	"ConfigMap":                OrderConfiguration,
+	"PersistentVolumeClaim":    OrderConfiguration,
+	"Service":                  OrderServices,
+	"NetworkPolicy":            OrderServices,
+	"ImageStream":              OrderBuilds,
+	"BuildConfig":              OrderBuilds,
+	"Deployment":               OrderWorkloads,
+	"DeploymentConfig":         OrderWorkloads,
+	"StatefulSet":              OrderWorkloads,
+	"DaemonSet":                OrderWorkloads,
+	"ReplicaSet":               OrderWorkloads,
+	"Job":                      OrderWorkloads,
+	"CronJob":                  OrderWorkloads,
+	"Pod":                      OrderWorkloads,
+	"HorizontalPodAutoscaler":  OrderWorkloads,
+	"PodDisruptionBudget":      OrderWorkloads,
+	"Route":                    OrderRoutes,
+	"Ingress":                  OrderRoutes,
+}
+
+type applier struct {
+	writer    *resourceWriter
+	order     map[reflect.Type]int
+	kindOrder map[string]int
+}
+
+// NewApplier creates an applier that uses the provided resource writer to apply a map of resource deltas
+// added and updated resources are written in dependency order of their kind, and removed resources in reverse order
+func NewApplier(writer *resourceWriter) *applier {
+	return &applier{
+		writer:    writer,
+		order:     make(map[reflect.Type]int),
+		kindOrder: make(map[string]int),
+	}
+}
+
+// WithOrder sets the rank of the provided resource type, overriding any default rank based on its kind
+// types that have no rank of their own are applied last, with a rank of OrderCustomResources
+func (this *applier) WithOrder(resourceType reflect.Type, rank int) *applier {
+	this.order[resourceType] = rank
+	return this
+}
+
+// WithKindOrder sets the rank of the provided kind, overriding its default rank
+// this applies to both typed and unstructured resources, unless a rank is set for their type through WithOrder
+func (this *applier) WithKindOrder(kind string, rank int) *applier {
+	this.kindOrder[kind] = rank
+	return this
+}
+
+// ApplyReport holds the results of the resources added, updated and removed by an applier, in the order they were written
+type ApplyReport struct {
+	Added   Report
+	Updated Report
+	Removed Report
+}
+
+// HasChanges returns true if any of the resources was successfully added, updated or removed
+func (this *ApplyReport) HasChanges() bool {
+	return this.Added.HasChanges() || this.Updated.HasChanges() || this.Removed.HasChanges()
+}
+
+// Apply adds, updates and removes the resources in the provided deltas, using the deployed map to find updated counterparts
+// unless the writer is configured to continue on error, it stops at the first failure
+// the boolean result is true if any changes were made
+func (this *applier) Apply(deployed map[reflect.Type][]resource.KubernetesResource, deltas map[reflect.Type]compare.ResourceDelta) (bool, error) {
+	report, err := this.ApplyWithReport(deployed, deltas)
+	return report.HasChanges(), err
+}
+
+// ApplyWithReport applies the provided deltas like Apply, and returns the result for each of the resources
+func (this *applier) ApplyWithReport(deployed map[reflect.Type][]resource.KubernetesResource, deltas map[reflect.Type]compare.ResourceDelta) (ApplyReport, error) {
+	var steps []applyStep
+	for resourceType, delta := range deltas {
+		steps = append(steps, applyStep{
+			rank:     this.rank(resourceType),
+			name:     resourceType.String(),
+			deployed: deployed[resourceType],
+			delta:    delta,
+		})
+	}
+	return this.applySteps(steps)
+}
+
+// ApplyUnstructured is the equivalent of Apply for unstructured resources, organized by GroupVersionKind
+// kinds are ranked the same way as typed resources of the same kind
+func (this *applier) ApplyUnstructured(deployed map[schema.GroupVersionKind][]resource.KubernetesResource, deltas map[schema.GroupVersionKind]compare.ResourceDelta) (bool, error) {
+	report, err := this.ApplyUnstructuredWithReport(deployed, deltas)
+	return report.HasChanges(), err
+}
+
+// ApplyUnstructuredWithReport applies the provided deltas like ApplyUnstructured, and returns the result for each of the resources
+func (this *applier) ApplyUnstructuredWithReport(deployed map[schema.GroupVersionKind][]resource.KubernetesResource, deltas map[schema.GroupVersionKind]compare.ResourceDelta) (ApplyReport, error) {
+	var steps []applyStep
+	for gvk, delta := range deltas {
+		steps = append(steps, applyStep{
+			rank:     this.rankKind(gvk.Kind),
+			name:     gvk.String(),
+			deployed: deployed[gvk],
+			delta:    delta,
+		})
+	}
+	return this.applySteps(steps)
+}
+
+type applyStep struct {
+	rank     int
+	name     string
+	deployed []resource.KubernetesResource
+	delta    compare.ResourceDelta
+}
+
+func (this *applier) applySteps(steps []applyStep) (ApplyReport, error) {
+	sort.SliceStable(steps, func(i, j int) bool {
+		if steps[i].rank != steps[j].rank {
+			return steps[i].rank < steps[j].rank
+		}
+		return steps[i].name < steps[j].name
+	})
+	report := ApplyReport{}
+	var errs []error
+	for _, step := range steps {
+		added, err := this.writer.AddResourcesWithReport(step.delta.Added)
+		report.Added.Results = append(report.Added.Results, added.Results...)
+		if err != nil {
+			if !this.writer.continueOnError {
+				return report, err
+			}
+			errs = append(errs, err)
+		}
+		updated, err := this.writer.UpdateResourcesWithReport(step.deployed, step.delta.Updated)
+		report.Updated.Results = append(report.Updated.Results, updated.Results...)
+		if err != nil {
+			if !this.writer.continueOnError {
+				return report, err
+			}
+			errs = append(errs, err)
+		}
+	}
+	for index := len(steps) - 1; index >= 0; index-- {
+		removed, err := this.writer.RemoveResourcesWithReport(steps[index].delta.Removed)
+		report.Removed.Results = append(report.Removed.Results, removed.Results...)
+		if err != nil {
+			if !this.writer.continueOnError {
+				return report, err
+			}
+			errs = append(errs, err)
+		}
+	}
+	return report, utilerrors.NewAggregate(errs)
+}
+
+func (this *applier) rank(resourceType reflect.Type) int {
+	if rank, found := this.order[resourceType]; found {
+		return rank
+	}
+	return this.rankKind(resourceType.Name())
+}
+
+func (this *applier) rankKind(kind string) int {
+	if rank, found := this.kindOrder[kind]; found {
+		return rank
+	}
+	if rank, found := defaultKindOrder[kind]; found {
+		return rank
+	}
+	return OrderCustomResources
+}
@@ -0,0 +1,53 @@
+package write
+
+import (
+	"github.com/RHsyseng/operator-utils/pkg/resource"
+	"github.com/RHsyseng/operator-utils/pkg/resource/compare"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestApplyOrder(t *testing.T) {
+	deltas := map[reflect.Type]compare.ResourceDelta{
+		reflect.TypeOf(routev1.Route{}): {
+			Added: []resource.KubernetesResource{&routev1.Route{ObjectMeta: v1.ObjectMeta{Name: "route1", Namespace: "namespace"}}},
+		},
+		reflect.TypeOf(appsv1.Deployment{}): {
+			Added: []resource.KubernetesResource{&appsv1.Deployment{ObjectMeta: v1.ObjectMeta{Name: "deployment1", Namespace: "namespace"}}},
+		},
+		reflect.TypeOf(corev1.Service{}): {
+			Added: []resource.KubernetesResource{&corev1.Service{ObjectMeta: v1.ObjectMeta{Name: "service1", Namespace: "namespace"}}},
+		},
+		reflect.TypeOf(corev1.ServiceAccount{}): {
+			Removed: []resource.KubernetesResource{&corev1.ServiceAccount{ObjectMeta: v1.ObjectMeta{Name: "account1", Namespace: "namespace"}}},
+		},
+		reflect.TypeOf(corev1.Secret{}): {
+			Removed: []resource.KubernetesResource{&corev1.Secret{ObjectMeta: v1.ObjectMeta{Name: "secret1", Namespace: "namespace"}}},
+		},
+	}
+
+	writer := New(fake.NewFakeClientWithScheme(getScheme(t))).WithDryRun()
+	changed, err := NewApplier(writer).Apply(nil, deltas)
+	assert.Nil(t, err, "Expect no errors in dry run")
+	assert.True(t, changed, "Expected changes to be planned")
+	assert.Equal(t, []string{"Create service1", "Create deployment1", "Create route1", "Delete secret1", "Delete account1"}, getPlanNames(writer.Plan()))
+
+	writer = New(fake.NewFakeClientWithScheme(getScheme(t))).WithDryRun()
+	_, err = NewApplier(writer).WithOrder(reflect.TypeOf(routev1.Route{}), OrderNamespaces).Apply(nil, deltas)
+	assert.Nil(t, err, "Expect no errors in dry run")
+	assert.Equal(t, "Create route1", getPlanNames(writer.Plan())[0], "Expected registered order to take precedence")
+}
+
+func getPlanNames(plan []Operation) []string {
+	var names []string
+	for _, operation := range plan {
+		names = append(names, string(operation.Action)+" "+operation.Name)
+	}
+	return names
+}
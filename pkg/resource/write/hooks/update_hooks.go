@@ -1,12 +1,15 @@
 package hooks
 
 import (
+	"context"
 	"github.com/RHsyseng/operator-utils/pkg/resource"
+	imagev1 "github.com/openshift/api/image/v1"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	"reflect"
 )
 
-type hookFunc = func(existing resource.KubernetesResource, requested resource.KubernetesResource) error
+type hookFunc = func(ctx context.Context, existing resource.KubernetesResource, requested resource.KubernetesResource) error
 
 type UpdateHookMap struct {
 	DefaultHook hookFunc
@@ -14,37 +17,96 @@ type UpdateHookMap struct {
 }
 
 func DefaultUpdateHooks() *UpdateHookMap {
-	hookMap := make(map[reflect.Type]func(existing resource.KubernetesResource, requested resource.KubernetesResource) error)
+	hookMap := make(map[reflect.Type]hookFunc)
 	hookMap[reflect.TypeOf(corev1.Service{})] = serviceHook
+	hookMap[reflect.TypeOf(corev1.PersistentVolumeClaim{})] = persistentVolumeClaimHook
+	hookMap[reflect.TypeOf(imagev1.ImageStream{})] = imageStreamHook
+	hookMap[reflect.TypeOf(apiextensionsv1beta1.CustomResourceDefinition{})] = customResourceDefinitionHook
 	return &UpdateHookMap{
 		DefaultHook: defaultHook,
 		HookMap:     hookMap,
 	}
 }
 
-func (this *UpdateHookMap) Trigger(existing resource.KubernetesResource, requested resource.KubernetesResource) error {
+func (this *UpdateHookMap) Trigger(ctx context.Context, existing resource.KubernetesResource, requested resource.KubernetesResource) error {
 	function := this.HookMap[reflect.ValueOf(existing).Elem().Type()]
 	if function == nil {
 		function = this.DefaultHook
 	}
-	return function(existing, requested)
+	return function(ctx, existing, requested)
 }
 
-func defaultHook(existing resource.KubernetesResource, requested resource.KubernetesResource) error {
+func defaultHook(ctx context.Context, existing resource.KubernetesResource, requested resource.KubernetesResource) error {
 	requested.SetResourceVersion(existing.GetResourceVersion())
 	requested.GetObjectKind().SetGroupVersionKind(existing.GetObjectKind().GroupVersionKind())
 	return nil
 }
 
-func serviceHook(existing resource.KubernetesResource, requested resource.KubernetesResource) error {
+func serviceHook(ctx context.Context, existing resource.KubernetesResource, requested resource.KubernetesResource) error {
 	existingService := existing.(*corev1.Service)
 	requestedService := requested.(*corev1.Service)
 	if requestedService.Spec.ClusterIP == "" {
 		requestedService.Spec.ClusterIP = existingService.Spec.ClusterIP
 	}
-	err := defaultHook(existing, requested)
+	err := defaultHook(ctx, existing, requested)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+func persistentVolumeClaimHook(ctx context.Context, existing resource.KubernetesResource, requested resource.KubernetesResource) error {
+	existingClaim := existing.(*corev1.PersistentVolumeClaim)
+	requestedClaim := requested.(*corev1.PersistentVolumeClaim)
+	//Only the requested storage may change once a claim is created, so carry over immutable and bound values
+	//values are copied, so that the requested claim shares no memory with the existing one
+	existingSpec := existingClaim.Spec.DeepCopy()
+	requestedClaim.Spec.VolumeName = existingSpec.VolumeName
+	requestedClaim.Spec.StorageClassName = existingSpec.StorageClassName
+	requestedClaim.Spec.AccessModes = existingSpec.AccessModes
+	requestedClaim.Spec.VolumeMode = existingSpec.VolumeMode
+	requestedClaim.Spec.Selector = existingSpec.Selector
+	requestedClaim.Spec.DataSource = existingSpec.DataSource
+	if len(requestedClaim.Finalizers) == 0 {
+		requestedClaim.Finalizers = append([]string{}, existingClaim.Finalizers...)
+	}
+	for _, annotation := range resource.ClaimBindAnnotations {
+		value, found := existingClaim.Annotations[annotation]
+		if _, requestedFound := requestedClaim.Annotations[annotation]; found && !requestedFound {
+			if requestedClaim.Annotations == nil {
+				requestedClaim.Annotations = make(map[string]string)
+			}
+			requestedClaim.Annotations[annotation] = value
+		}
+	}
+	return defaultHook(ctx, existing, requested)
+}
+
+func imageStreamHook(ctx context.Context, existing resource.KubernetesResource, requested resource.KubernetesResource) error {
+	existingStream := existing.(*imagev1.ImageStream)
+	requestedStream := requested.(*imagev1.ImageStream)
+	//Tag generations and status are managed by the image controllers, and record the history of imported images
+	existingTags := make(map[string]imagev1.TagReference)
+	for _, tag := range existingStream.Spec.Tags {
+		existingTags[tag.Name] = tag
+	}
+	for index := range requestedStream.Spec.Tags {
+		requestedTag := &requestedStream.Spec.Tags[index]
+		if existingTag, found := existingTags[requestedTag.Name]; found && requestedTag.Generation == nil {
+			requestedTag.Generation = existingTag.Generation
+		}
+	}
+	if requestedStream.Spec.DockerImageRepository == "" {
+		requestedStream.Spec.DockerImageRepository = existingStream.Spec.DockerImageRepository
+	}
+	requestedStream.Status = existingStream.Status
+	return defaultHook(ctx, existing, requested)
+}
+
+func customResourceDefinitionHook(ctx context.Context, existing resource.KubernetesResource, requested resource.KubernetesResource) error {
+	existingCRD := existing.(*apiextensionsv1beta1.CustomResourceDefinition)
+	requestedCRD := requested.(*apiextensionsv1beta1.CustomResourceDefinition)
+	//Stored versions must be kept, or the API server rejects the update for dropping a version that has persisted objects
+	requestedCRD.Status = existingCRD.Status
+	return defaultHook(ctx, existing, requested)
+}
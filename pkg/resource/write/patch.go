@@ -0,0 +1,301 @@
+package write
+
+import (
+	"encoding/json"
+	"github.com/RHsyseng/operator-utils/pkg/resource"
+	jsonpatch "github.com/evanphx/json-patch"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+	"reflect"
+)
+
+var serverManagedMetadata = []string{"creationTimestamp", "deletionTimestamp", "deletionGracePeriodSeconds", "generation", "resourceVersion", "selfLink", "uid", "managedFields"}
+
+// mergeIntoExisting computes a patch that carries the fields set on the requested object over to the existing counterpart,
+// and returns a new object holding the result of applying that patch to the existing object, to be sent as a full update
+// the requested object itself is left unchanged
+// a strategic merge patch is used for built-in types, and a JSON merge patch for custom and OpenShift types
+// fields that were previously requested, as recorded in the LastAppliedAnnotation, and are missing from the requested object are removed,
+// while values set by other parties are preserved
+// the boolean result is false, and no object is returned, if applying the patch makes no change to the existing object
+func mergeIntoExisting(existing resource.KubernetesResource, requested resource.KubernetesResource) (resource.KubernetesResource, bool, error) {
+	existingJSON, err := json.Marshal(existing)
+	if err != nil {
+		return nil, false, err
+	}
+	requestedJSON, err := marshalRequestedFields(requested)
+	if err != nil {
+		return nil, false, err
+	}
+	lastApplied, found := existing.GetAnnotations()[resource.LastAppliedAnnotation]
+	if !isUnstructured(requested) {
+		requestedJSON, err = removeUnsetStrings(requestedJSON, []byte(lastApplied), existingJSON)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	//Without a record of previously requested fields, nothing is removed
+	originalJSON := requestedJSON
+	if found {
+		originalJSON = []byte(lastApplied)
+	}
+	recordJSON, err := lastAppliedRecord(requested, requestedJSON)
+	if err != nil {
+		return nil, false, err
+	}
+	modifiedJSON, err := withLastApplied(requestedJSON, recordJSON)
+	if err != nil {
+		return nil, false, err
+	}
+	var patched []byte
+	if isBuiltInType(requested) {
+		dataStruct := reflect.New(reflect.ValueOf(requested).Elem().Type()).Interface()
+		patchMeta, err := strategicpatch.NewPatchMetaFromStruct(dataStruct)
+		if err != nil {
+			return nil, false, err
+		}
+		patch, err := strategicpatch.CreateThreeWayMergePatch(originalJSON, modifiedJSON, existingJSON, patchMeta, true)
+		if err != nil {
+			return nil, false, err
+		}
+		if isEmptyPatch(patch) {
+			return nil, false, nil
+		}
+		patched, err = strategicpatch.StrategicMergePatch(existingJSON, patch, dataStruct)
+		if err != nil {
+			return nil, false, err
+		}
+	} else {
+		patch, err := jsonmergepatch.CreateThreeWayJSONMergePatch(originalJSON, modifiedJSON, existingJSON)
+		if err != nil {
+			return nil, false, err
+		}
+		if isEmptyPatch(patch) {
+			return nil, false, nil
+		}
+		patched, err = jsonpatch.MergePatch(existingJSON, patch)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	unchanged, err := isSameJSON(existingJSON, patched)
+	if err != nil || unchanged {
+		//Patches may hold nothing but directives, such as the order of list items that are already in order
+		return nil, false, err
+	}
+	merged := reflect.New(reflect.ValueOf(requested).Elem().Type()).Interface().(resource.KubernetesResource)
+	err = json.Unmarshal(patched, merged)
+	if err != nil {
+		return nil, false, err
+	}
+	return merged, true, nil
+}
+
+// setLastApplied records the requested fields of the object in its LastAppliedAnnotation
+func setLastApplied(object resource.KubernetesResource) error {
+	requestedJSON, err := marshalRequestedFields(object)
+	if err != nil {
+		return err
+	}
+	recordJSON, err := lastAppliedRecord(object, requestedJSON)
+	if err != nil {
+		return err
+	}
+	annotations := object.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[resource.LastAppliedAnnotation] = string(recordJSON)
+	object.SetAnnotations(annotations)
+	return nil
+}
+
+func isUnstructured(object resource.KubernetesResource) bool {
+	_, ok := object.(runtime.Unstructured)
+	return ok
+}
+
+func isBuiltInType(object resource.KubernetesResource) bool {
+	if isUnstructured(object) {
+		//The scheme reports the kind of any unstructured object, but has no struct to derive patch strategies from
+		return false
+	}
+	_, _, err := scheme.Scheme.ObjectKinds(object)
+	return err == nil
+}
+
+// marshalRequestedFields serializes the fields set on the object, leaving out null values, type metadata, status,
+// server-managed metadata and the LastAppliedAnnotation itself
+func marshalRequestedFields(object resource.KubernetesResource) ([]byte, error) {
+	objectJSON, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	objectMap := make(map[string]interface{})
+	err = json.Unmarshal(objectJSON, &objectMap)
+	if err != nil {
+		return nil, err
+	}
+	//Status is owned by the server and its zero values would otherwise be merged into the existing object
+	delete(objectMap, "status")
+	//Type metadata is not always set on requested objects, and is not changed by an update
+	delete(objectMap, "apiVersion")
+	delete(objectMap, "kind")
+	if metadata, ok := objectMap["metadata"].(map[string]interface{}); ok {
+		for _, field := range serverManagedMetadata {
+			delete(metadata, field)
+		}
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			delete(annotations, resource.LastAppliedAnnotation)
+		}
+	}
+	removeNulls(objectMap)
+	return json.Marshal(objectMap)
+}
+
+// lastAppliedRecord returns the requested fields to record in the LastAppliedAnnotation
+// empty strings of typed objects are not recorded, since they cannot be told apart from fields that are not set
+func lastAppliedRecord(object resource.KubernetesResource, requestedJSON []byte) ([]byte, error) {
+	if isUnstructured(object) {
+		return requestedJSON, nil
+	}
+	objectMap := make(map[string]interface{})
+	err := json.Unmarshal(requestedJSON, &objectMap)
+	if err != nil {
+		return nil, err
+	}
+	removeEmptyStrings(objectMap)
+	return json.Marshal(objectMap)
+}
+
+func withLastApplied(requestedJSON []byte, recordJSON []byte) ([]byte, error) {
+	objectMap := make(map[string]interface{})
+	err := json.Unmarshal(requestedJSON, &objectMap)
+	if err != nil {
+		return nil, err
+	}
+	metadata, ok := objectMap["metadata"].(map[string]interface{})
+	if !ok {
+		metadata = make(map[string]interface{})
+		objectMap["metadata"] = metadata
+	}
+	annotations, ok := metadata["annotations"].(map[string]interface{})
+	if !ok {
+		annotations = make(map[string]interface{})
+		metadata["annotations"] = annotations
+	}
+	annotations[resource.LastAppliedAnnotation] = string(recordJSON)
+	return json.Marshal(objectMap)
+}
+
+func removeNulls(value interface{}) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, entry := range typed {
+			if entry == nil {
+				//Unset fields without omitempty serialize as null, which a merge patch would take as a request to delete them
+				delete(typed, key)
+			} else {
+				removeNulls(entry)
+			}
+		}
+	case []interface{}:
+		for _, entry := range typed {
+			removeNulls(entry)
+		}
+	}
+}
+
+// removeUnsetStrings removes empty strings from the requested fields of a typed object where they would replace a value set by another party
+// fields without omitempty serialize as empty strings when not set, so an empty string is only kept if the existing object has no value,
+// or if it replaces a value that was previously requested, as recorded in the last applied fields
+func removeUnsetStrings(requestedJSON []byte, lastAppliedJSON []byte, existingJSON []byte) ([]byte, error) {
+	var requestedMap, lastAppliedMap, existingMap map[string]interface{}
+	err := json.Unmarshal(requestedJSON, &requestedMap)
+	if err != nil {
+		return nil, err
+	}
+	if len(lastAppliedJSON) > 0 {
+		err = json.Unmarshal(lastAppliedJSON, &lastAppliedMap)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = json.Unmarshal(existingJSON, &existingMap)
+	if err != nil {
+		return nil, err
+	}
+	removeUnsetStringValues(requestedMap, lastAppliedMap, existingMap)
+	return json.Marshal(requestedMap)
+}
+
+func removeUnsetStringValues(requested interface{}, lastApplied interface{}, existing interface{}) {
+	switch typed := requested.(type) {
+	case map[string]interface{}:
+		lastAppliedMap, _ := lastApplied.(map[string]interface{})
+		existingMap, _ := existing.(map[string]interface{})
+		for key, entry := range typed {
+			if entry == "" {
+				if isNonEmptyString(existingMap[key]) && !isNonEmptyString(lastAppliedMap[key]) {
+					delete(typed, key)
+				}
+			} else {
+				removeUnsetStringValues(entry, lastAppliedMap[key], existingMap[key])
+			}
+		}
+	case []interface{}:
+		lastAppliedSlice, _ := lastApplied.([]interface{})
+		existingSlice, _ := existing.([]interface{})
+		for index, entry := range typed {
+			var lastAppliedEntry, existingEntry interface{}
+			if index < len(lastAppliedSlice) {
+				lastAppliedEntry = lastAppliedSlice[index]
+			}
+			if index < len(existingSlice) {
+				existingEntry = existingSlice[index]
+			}
+			removeUnsetStringValues(entry, lastAppliedEntry, existingEntry)
+		}
+	}
+}
+
+func removeEmptyStrings(value interface{}) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, entry := range typed {
+			if entry == "" {
+				delete(typed, key)
+			} else {
+				removeEmptyStrings(entry)
+			}
+		}
+	case []interface{}:
+		for _, entry := range typed {
+			removeEmptyStrings(entry)
+		}
+	}
+}
+
+func isNonEmptyString(value interface{}) bool {
+	text, ok := value.(string)
+	return ok && text != ""
+}
+
+func isSameJSON(json1 []byte, json2 []byte) (bool, error) {
+	var object1, object2 interface{}
+	err := json.Unmarshal(json1, &object1)
+	if err != nil {
+		return false, err
+	}
+	err = json.Unmarshal(json2, &object2)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(object1, object2), nil
+}
+
+func isEmptyPatch(patch []byte) bool {
+	return string(patch) == "{}"
+}
@@ -0,0 +1,39 @@
+package write
+
+import (
+	"github.com/RHsyseng/operator-utils/pkg/resource"
+	"github.com/RHsyseng/operator-utils/pkg/resource/compare"
+	"reflect"
+)
+
+type Action string
+
+const (
+	Create Action = "Create"
+	Update Action = "Update"
+	Delete Action = "Delete"
+)
+
+// Operation describes a call that the writer would have made, had it not been in dry-run mode
+type Operation struct {
+	Action    Action                      `json:"action"`
+	Kind      string                      `json:"kind"`
+	Namespace string                      `json:"namespace,omitempty"`
+	Name      string                      `json:"name"`
+	Diffs     []compare.Difference        `json:"diffs,omitempty"`
+	Resource  resource.KubernetesResource `json:"-"`
+}
+
+func newOperation(action Action, res resource.KubernetesResource) Operation {
+	kind := res.GetObjectKind().GroupVersionKind().Kind
+	if kind == "" {
+		kind = reflect.ValueOf(res).Elem().Type().Name()
+	}
+	return Operation{
+		Action:    action,
+		Kind:      kind,
+		Namespace: res.GetNamespace(),
+		Name:      res.GetName(),
+		Resource:  res,
+	}
+}
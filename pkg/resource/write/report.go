@@ -0,0 +1,89 @@
+package write
+
+import (
+	"github.com/RHsyseng/operator-utils/pkg/resource"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+type ResultStatus string
+
+const (
+	// Succeeded means the resource was created, updated or removed
+	Succeeded ResultStatus = "Succeeded"
+	// Failed means the call for the resource returned an error
+	Failed ResultStatus = "Failed"
+	// Skipped means no call was made for the resource, because there was nothing to change or an earlier resource failed
+	Skipped ResultStatus = "Skipped"
+)
+
+// Result describes the outcome of adding, updating or removing a single resource
+type Result struct {
+	Resource resource.KubernetesResource
+	Status   ResultStatus
+	Error    error
+}
+
+// Report lists the result for each of the resources provided to the writer, in the order they were processed
+type Report struct {
+	Results []Result
+}
+
+// HasChanges returns true if any of the resources was successfully added, updated or removed
+func (this *Report) HasChanges() bool {
+	for _, result := range this.Results {
+		if result.Status == Succeeded {
+			return true
+		}
+	}
+	return false
+}
+
+// Failed returns the results of all resources that could not be written
+func (this *Report) Failed() []Result {
+	var failed []Result
+	for _, result := range this.Results {
+		if result.Status == Failed {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Succeeded returns the results of all resources that were successfully written
+func (this *Report) Succeeded() []Result {
+	var succeeded []Result
+	for _, result := range this.Results {
+		if result.Status == Succeeded {
+			succeeded = append(succeeded, result)
+		}
+	}
+	return succeeded
+}
+
+// Err returns nil if no resource failed, the error itself for a single failure, or an aggregate of all the errors
+func (this *Report) Err() error {
+	var errs []error
+	for _, result := range this.Failed() {
+		errs = append(errs, result.Error)
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (this *Report) add(res resource.KubernetesResource, changed bool, err error) {
+	status := Skipped
+	if err != nil {
+		status = Failed
+	} else if changed {
+		status = Succeeded
+	}
+	this.Results = append(this.Results, Result{Resource: res, Status: status, Error: err})
+}
+
+func (this *Report) skip(resources []resource.KubernetesResource) {
+	for index := range resources {
+		this.add(resources[index], false, nil)
+	}
+}
@@ -2,25 +2,54 @@ package write
 
 import (
 	"context"
+	"fmt"
 	"github.com/RHsyseng/operator-utils/pkg/resource"
+	"github.com/RHsyseng/operator-utils/pkg/resource/compare"
 	"github.com/RHsyseng/operator-utils/pkg/resource/write/hooks"
 	newerror "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"reflect"
 	clientv1 "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"strings"
 )
 
 type UpdateHooks interface {
-	Trigger(existing resource.KubernetesResource, requested resource.KubernetesResource) error
+	Trigger(ctx context.Context, existing resource.KubernetesResource, requested resource.KubernetesResource) error
 }
 
 type resourceWriter struct {
 	writer          clientv1.Writer
+	ctx             context.Context
 	ownerRefs       []metav1.OwnerReference
 	ownerController metav1.Object
 	scheme          *runtime.Scheme
 	updateHooks     UpdateHooks
+	patchUpdates    bool
+	conflictReader  clientv1.Reader
+	conflictBackoff wait.Backoff
+	continueOnError bool
+	dryRun          bool
+	comparator      compare.ResourceComparator
+	plan            []Operation
+}
+
+// ConflictError reports the resources that could not be updated due to conflicts, even after retrying
+type ConflictError struct {
+	Resources []resource.KubernetesResource
+}
+
+func (this *ConflictError) Error() string {
+	var names []string
+	for _, res := range this.Resources {
+		names = append(names, fmt.Sprintf("%s/%s", res.GetNamespace(), res.GetName()))
+	}
+	return fmt.Sprintf("Failed to update resources due to conflicts: %s", strings.Join(names, ", "))
 }
 
 // New creates a resourceWriter object that can be used to add/update/remove kubernetes resources
@@ -28,10 +57,18 @@ type resourceWriter struct {
 func New(writer clientv1.Writer) *resourceWriter {
 	return &resourceWriter{
 		writer:      writer,
+		ctx:         context.TODO(),
 		updateHooks: hooks.DefaultUpdateHooks(),
+		comparator:  compare.DefaultComparator(),
 	}
 }
 
+// WithContext sets the context passed to underlying calls and update hooks, so that their deadline and cancellation are honored
+func (this *resourceWriter) WithContext(ctx context.Context) *resourceWriter {
+	this.ctx = ctx
+	return this
+}
+
 // WithOwnerReferences allows owner references to be set on any object that's added or updated
 // calling this function removes any owner controller that may have been configured
 func (this *resourceWriter) WithOwnerReferences(ownerRefs ...metav1.OwnerReference) *resourceWriter {
@@ -57,27 +94,107 @@ func (this *resourceWriter) WithCustomUpdateHooks(updateHooks UpdateHooks) *reso
 	return this
 }
 
+// WithPatchUpdates makes update calls merge the requested fields into the deployed counterpart instead of replacing it
+// a strategic merge patch is computed for built-in types and a JSON merge patch for custom and OpenShift types,
+// so fields set by admission webhooks or other controllers are preserved, and no update is made if the patch is empty
+// the requested fields are recorded in the LastAppliedAnnotation of added and updated resources, so fields that are no longer requested get removed
+// this mode is merge-then-update, not a patch call: the controller-runtime client used here cannot send patches, so the patch is applied
+// locally to a copy of the deployed counterpart, and that copy is sent as a full update, leaving the requested object unchanged
+// the update carries the resource version of the counterpart, so it fails with a conflict, rather than overwriting the change,
+// if another party has written the resource since it was read
+// typed objects cannot tell an empty string apart from an unset field, so an empty string is only applied where the deployed value is empty
+// or was itself requested before, and is otherwise left to the party that set it; use unstructured objects to set an empty string explicitly
+func (this *resourceWriter) WithPatchUpdates() *resourceWriter {
+	this.patchUpdates = true
+	return this
+}
+
+// WithConflictRetry makes update calls that fail with a conflict retry according to the provided backoff
+// on each attempt, the live object is loaded through the provided reader and update hooks are triggered again
+func (this *resourceWriter) WithConflictRetry(reader clientv1.Reader, backoff wait.Backoff) *resourceWriter {
+	this.conflictReader = reader
+	this.conflictBackoff = backoff
+	return this
+}
+
+// WithContinueOnError makes add, update and remove calls carry on with the remaining resources after a failure
+// instead of stopping at the first error, in which case the returned error aggregates all failures
+func (this *resourceWriter) WithContinueOnError() *resourceWriter {
+	this.continueOnError = true
+	return this
+}
+
+// WithDryRun makes the writer record the operations it would perform, instead of making any changes to the cluster
+// results are reported as if each call had succeeded, and the recorded operations are available through Plan
+// ownership, hooks and patch mode are applied to copies, so the provided objects are left unchanged, and each operation holds the copy that would have been written
+func (this *resourceWriter) WithDryRun() *resourceWriter {
+	this.dryRun = true
+	return this
+}
+
+// WithComparator sets the comparator used to find the differences of updates recorded in dry-run mode
+// it should match the comparator used to compute the deltas, so the plan reports the same differences that caused each update
+func (this *resourceWriter) WithComparator(comparator compare.ResourceComparator) *resourceWriter {
+	this.comparator = comparator
+	return this
+}
+
+// Plan returns the operations recorded in dry-run mode, in the order they would have been performed
+// operations accumulate across calls until ResetPlan is called
+func (this *resourceWriter) Plan() []Operation {
+	return this.plan
+}
+
+// ResetPlan discards the operations recorded so far, so the writer can be reused to plan another set of changes
+func (this *resourceWriter) ResetPlan() {
+	this.plan = nil
+}
+
 // AddResources sets ownership as/if configured, and then uses the writer to create them
 // the boolean result is true if any changes were made
 func (this *resourceWriter) AddResources(resources []resource.KubernetesResource) (bool, error) {
-	var added bool
+	report, err := this.AddResourcesWithReport(resources)
+	return report.HasChanges(), err
+}
+
+// AddResourcesWithReport creates the provided resources like AddResources, and returns the result for each of them
+func (this *resourceWriter) AddResourcesWithReport(resources []resource.KubernetesResource) (Report, error) {
+	report := Report{}
 	for index := range resources {
-		requested := resources[index]
-		if this.ownerRefs != nil {
-			requested.SetOwnerReferences(this.ownerRefs)
-		} else if this.canSetOwnerRef(requested, this.ownerController) {
-			err := controllerutil.SetControllerReference(this.ownerController, requested, this.scheme)
-			if err != nil {
-				return added, err
-			}
+		err := this.addResource(resources[index])
+		report.add(resources[index], err == nil, err)
+		if err != nil && !this.continueOnError {
+			report.skip(resources[index+1:])
+			break
 		}
-		err := this.writer.Create(context.TODO(), requested)
+	}
+	return report, report.Err()
+}
+
+func (this *resourceWriter) addResource(requested resource.KubernetesResource) error {
+	if this.dryRun {
+		//A preview must not change the objects provided by the caller
+		requested = requested.DeepCopyObject().(resource.KubernetesResource)
+	}
+	if this.ownerRefs != nil {
+		requested.SetOwnerReferences(this.ownerRefs)
+	} else if this.canSetOwnerRef(requested, this.ownerController) {
+		err := controllerutil.SetControllerReference(this.ownerController, requested, this.scheme)
+		if err != nil {
+			return err
+		}
+	}
+	if this.patchUpdates {
+		err := setLastApplied(requested)
 		if err != nil {
-			return added, err
+			return err
 		}
-		added = true
 	}
-	return added, nil
+	if this.dryRun {
+		this.plan = append(this.plan, newOperation(Create, requested))
+		return nil
+	}
+	return this.writer.Create(this.ctx, requested)
 }
 
 func (this *resourceWriter) canSetOwnerRef(resource metav1.Object, owner metav1.Object) bool {
@@ -91,53 +208,163 @@ func (this *resourceWriter) canSetOwnerRef(resource metav1.Object, owner metav1.
 }
 
 // UpdateResources finds the updated counterpart for each of the provided resources in the existing array and uses it to set resource version and GVK
-// It also sets ownership as/if configured, and then uses the writer to update them
+// It also sets ownership as/if configured, merges into the counterpart in patch mode, and then uses the writer to update them
+// if conflict retry is configured, resources that still conflict after retrying are skipped and reported in a ConflictError
 // the boolean result is true if any changes were made
 func (this *resourceWriter) UpdateResources(existing []resource.KubernetesResource, resources []resource.KubernetesResource) (bool, error) {
-	var updated bool
+	report, err := this.UpdateResourcesWithReport(existing, resources)
+	return report.HasChanges(), err
+}
+
+// UpdateResourcesWithReport updates the provided resources like UpdateResources, and returns the result for each of them
+// the result of a resource that still conflicts after retrying holds a ConflictError for that resource
+func (this *resourceWriter) UpdateResourcesWithReport(existing []resource.KubernetesResource, resources []resource.KubernetesResource) (Report, error) {
+	report := Report{}
 	for index := range resources {
-		requested := resources[index]
-		var counterpart resource.KubernetesResource
-		for _, candidate := range existing {
-			if candidate.GetNamespace() == requested.GetNamespace() && candidate.GetName() == requested.GetName() {
-				counterpart = candidate
-				break
-			}
+		changed, err := this.updateWithCounterpart(existing, resources[index])
+		report.add(resources[index], changed, err)
+		if err != nil && !this.continueOnError && !isRetriedConflict(err) {
+			report.skip(resources[index+1:])
+			break
 		}
-		if counterpart == nil {
-			return updated, newerror.New("Failed to find a deployed counterpart to resource being updated")
+	}
+	return report, this.updateErr(report)
+}
+
+// updateErr combines the conflicts of all resources into a single ConflictError, unless another failure stopped the update
+func (this *resourceWriter) updateErr(report Report) error {
+	failed := report.Failed()
+	if len(failed) == 0 || this.continueOnError {
+		return report.Err()
+	}
+	var conflicted []resource.KubernetesResource
+	for _, result := range failed {
+		conflictErr, ok := result.Error.(*ConflictError)
+		if !ok {
+			return result.Error
 		}
-		err := this.updateHooks.Trigger(counterpart, requested)
-		if err != nil {
-			return updated, err
+		conflicted = append(conflicted, conflictErr.Resources...)
+	}
+	return &ConflictError{Resources: conflicted}
+}
+
+func (this *resourceWriter) updateWithCounterpart(existing []resource.KubernetesResource, requested resource.KubernetesResource) (bool, error) {
+	var counterpart resource.KubernetesResource
+	for _, candidate := range existing {
+		if candidate.GetNamespace() == requested.GetNamespace() && candidate.GetName() == requested.GetName() {
+			counterpart = candidate
+			break
 		}
-		if this.ownerRefs != nil {
-			requested.SetOwnerReferences(this.ownerRefs)
-		} else if this.ownerController != nil {
-			err := controllerutil.SetControllerReference(this.ownerController, requested, this.scheme)
-			if err != nil {
-				return updated, err
-			}
+	}
+	if counterpart == nil {
+		return false, newerror.New("Failed to find a deployed counterpart to resource being updated")
+	}
+	var original resource.KubernetesResource
+	if this.conflictReader != nil {
+		original = requested.DeepCopyObject().(resource.KubernetesResource)
+	}
+	changed, err := this.updateResource(counterpart, requested)
+	if err != nil && this.conflictReader != nil && errors.IsConflict(err) {
+		changed, err = this.retryOnConflict(original, requested)
+		if errors.IsConflict(err) {
+			return false, &ConflictError{Resources: []resource.KubernetesResource{requested}}
 		}
-		err = this.writer.Update(context.TODO(), requested)
+	}
+	return changed, err
+}
+
+func isRetriedConflict(err error) bool {
+	_, ok := err.(*ConflictError)
+	return ok
+}
+
+func (this *resourceWriter) updateResource(counterpart resource.KubernetesResource, requested resource.KubernetesResource) (bool, error) {
+	var diffs []compare.Difference
+	if this.dryRun {
+		//Differences are found before hooks fill in values from the counterpart, like the comparison that produced the delta,
+		//and a copy is modified instead of the object provided by the caller
+		_, diffs = this.comparator.CompareWithDiff(counterpart, requested)
+		requested = requested.DeepCopyObject().(resource.KubernetesResource)
+	}
+	err := this.updateHooks.Trigger(this.ctx, counterpart, requested)
+	if err != nil {
+		return false, err
+	}
+	if this.ownerRefs != nil {
+		requested.SetOwnerReferences(this.ownerRefs)
+	} else if this.ownerController != nil {
+		err := controllerutil.SetControllerReference(this.ownerController, requested, this.scheme)
 		if err != nil {
-			return updated, err
+			return false, err
+		}
+	}
+	if this.patchUpdates {
+		merged, changed, err := mergeIntoExisting(counterpart, requested)
+		if err != nil || !changed {
+			return false, err
 		}
-		updated = true
+		requested = merged
 	}
-	return updated, nil
+	if this.dryRun {
+		operation := newOperation(Update, requested)
+		operation.Diffs = diffs
+		this.plan = append(this.plan, operation)
+		return true, nil
+	}
+	err = this.writer.Update(this.ctx, requested)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (this *resourceWriter) retryOnConflict(original resource.KubernetesResource, requested resource.KubernetesResource) (bool, error) {
+	var changed bool
+	err := retry.RetryOnConflict(this.conflictBackoff, func() error {
+		if err := this.ctx.Err(); err != nil {
+			//Stop retrying once the context is done
+			return err
+		}
+		live := reflect.New(reflect.ValueOf(requested).Elem().Type()).Interface().(resource.KubernetesResource)
+		//Unstructured objects need their kind to be loaded
+		live.GetObjectKind().SetGroupVersionKind(original.GetObjectKind().GroupVersionKind())
+		err := this.conflictReader.Get(this.ctx, types.NamespacedName{Namespace: requested.GetNamespace(), Name: requested.GetName()}, live)
+		if err != nil {
+			return err
+		}
+		//Start over from the requested state, since hooks and patch mode have modified the object in the failed attempt
+		reflect.ValueOf(requested).Elem().Set(reflect.ValueOf(original.DeepCopyObject()).Elem())
+		changed, err = this.updateResource(live, requested)
+		return err
+	})
+	return changed, err
 }
 
 // RemoveResources removes each of the provided resources using the provided writer
 // the boolean result is true if any changes were made
 func (this *resourceWriter) RemoveResources(resources []resource.KubernetesResource) (bool, error) {
-	var removed bool
+	report, err := this.RemoveResourcesWithReport(resources)
+	return report.HasChanges(), err
+}
+
+// RemoveResourcesWithReport removes the provided resources like RemoveResources, and returns the result for each of them
+func (this *resourceWriter) RemoveResourcesWithReport(resources []resource.KubernetesResource) (Report, error) {
+	report := Report{}
 	for index := range resources {
-		err := this.writer.Delete(context.TODO(), resources[index])
-		if err != nil {
-			return removed, err
+		err := this.removeResource(resources[index])
+		report.add(resources[index], err == nil, err)
+		if err != nil && !this.continueOnError {
+			report.skip(resources[index+1:])
+			break
 		}
-		removed = true
 	}
-	return removed, nil
+	return report, report.Err()
+}
+
+func (this *resourceWriter) removeResource(res resource.KubernetesResource) error {
+	if this.dryRun {
+		this.plan = append(this.plan, newOperation(Delete, res))
+		return nil
+	}
+	return this.writer.Delete(this.ctx, res)
 }
@@ -2,14 +2,31 @@ package write
 
 import (
 	"context"
+	"fmt"
 	"github.com/RHsyseng/operator-utils/pkg/resource"
+	"github.com/RHsyseng/operator-utils/pkg/resource/compare"
+	"github.com/RHsyseng/operator-utils/pkg/resource/write/hooks"
+	imagev1 "github.com/openshift/api/image/v1"
+	routev1 "github.com/openshift/api/route/v1"
+	newerror "github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"reflect"
+	clientv1 "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"testing"
+	"time"
 )
 
 func TestFluentAPI(t *testing.T) {
@@ -91,9 +108,663 @@ func TestUpdateService(t *testing.T) {
 	assert.Equal(t, updatedService, existingService, "Expected Cluster IP to be set on the updating object")
 }
 
+func TestUpdatePersistentVolumeClaim(t *testing.T) {
+	scheme := getScheme(t)
+	client := fake.NewFakeClientWithScheme(scheme)
+	storageClassName := "standard"
+	volumeMode := corev1.PersistentVolumeFilesystem
+	existingClaim := corev1.PersistentVolumeClaim{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        "claim1",
+			Namespace:   "namespace",
+			Annotations: map[string]string{"pv.kubernetes.io/bind-completed": "yes"},
+			Finalizers:  []string{"kubernetes.io/pvc-protection"},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			StorageClassName: &storageClassName,
+			VolumeName:       "pv1",
+			VolumeMode:       &volumeMode,
+			Selector:         &v1.LabelSelector{MatchLabels: map[string]string{"volume": "pv1"}},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: apiresource.MustParse("1Gi")},
+			},
+		},
+	}
+	assert.Nil(t, client.Create(context.TODO(), &existingClaim), "Expect no errors mock creating object")
+
+	//Immutable fields of the request differ from the existing claim, and should be ignored
+	requestedStorageClassName := "fast"
+	requestedVolumeMode := corev1.PersistentVolumeBlock
+	requestedClaim := corev1.PersistentVolumeClaim{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "claim1",
+			Namespace: "namespace",
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+			StorageClassName: &requestedStorageClassName,
+			VolumeName:       "pv2",
+			VolumeMode:       &requestedVolumeMode,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: apiresource.MustParse("2Gi")},
+			},
+		},
+	}
+	updated, err := New(client).UpdateResources([]resource.KubernetesResource{&existingClaim}, []resource.KubernetesResource{&requestedClaim})
+	assert.Nil(t, err, "Expect no errors updating object")
+	assert.True(t, updated, "Object should be updated")
+
+	updatedClaim := corev1.PersistentVolumeClaim{}
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "claim1", Namespace: "namespace"}, &updatedClaim)
+	assert.Nil(t, err, "Expect no errors loading existing object")
+	assert.Equal(t, "pv1", updatedClaim.Spec.VolumeName, "Expected volume name to be carried over")
+	assert.Equal(t, &storageClassName, updatedClaim.Spec.StorageClassName, "Expected storage class to be carried over")
+	assert.Equal(t, existingClaim.Spec.AccessModes, updatedClaim.Spec.AccessModes, "Expected access modes to be carried over")
+	assert.Equal(t, &volumeMode, updatedClaim.Spec.VolumeMode, "Expected volume mode to be carried over")
+	assert.Equal(t, existingClaim.Spec.Selector, updatedClaim.Spec.Selector, "Expected selector to be carried over")
+	assert.Equal(t, existingClaim.Annotations, updatedClaim.Annotations, "Expected bind annotations to be carried over")
+	assert.Equal(t, existingClaim.Finalizers, updatedClaim.Finalizers, "Expected finalizers to be carried over")
+	storage := updatedClaim.Spec.Resources.Requests[corev1.ResourceStorage]
+	assert.Equal(t, "2Gi", storage.String(), "Expected requested storage to be updated")
+}
+
+func TestPersistentVolumeClaimHookCopiesValues(t *testing.T) {
+	storageClassName := "standard"
+	volumeMode := corev1.PersistentVolumeFilesystem
+	existingClaim := &corev1.PersistentVolumeClaim{
+		ObjectMeta: v1.ObjectMeta{
+			Name:       "claim1",
+			Namespace:  "namespace",
+			Finalizers: []string{"kubernetes.io/pvc-protection"},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			StorageClassName: &storageClassName,
+			VolumeMode:       &volumeMode,
+			Selector:         &v1.LabelSelector{MatchLabels: map[string]string{"volume": "pv1"}},
+		},
+	}
+	requestedClaim := &corev1.PersistentVolumeClaim{ObjectMeta: v1.ObjectMeta{Name: "claim1", Namespace: "namespace"}}
+	assert.Nil(t, hooks.DefaultUpdateHooks().Trigger(context.TODO(), existingClaim, requestedClaim), "Expect no errors triggering hooks")
+	assert.Equal(t, &volumeMode, requestedClaim.Spec.VolumeMode, "Expected volume mode to be carried over")
+
+	//Changes to the requested claim must not show up in the existing one
+	*requestedClaim.Spec.StorageClassName = "fast"
+	*requestedClaim.Spec.VolumeMode = corev1.PersistentVolumeBlock
+	requestedClaim.Spec.Selector.MatchLabels["volume"] = "pv2"
+	requestedClaim.Spec.AccessModes[0] = corev1.ReadWriteMany
+	requestedClaim.Finalizers[0] = "other"
+	assert.Equal(t, "standard", *existingClaim.Spec.StorageClassName, "Expected existing storage class to be unchanged")
+	assert.Equal(t, corev1.PersistentVolumeFilesystem, *existingClaim.Spec.VolumeMode, "Expected existing volume mode to be unchanged")
+	assert.Equal(t, "pv1", existingClaim.Spec.Selector.MatchLabels["volume"], "Expected existing selector to be unchanged")
+	assert.Equal(t, corev1.ReadWriteOnce, existingClaim.Spec.AccessModes[0], "Expected existing access modes to be unchanged")
+	assert.Equal(t, "kubernetes.io/pvc-protection", existingClaim.Finalizers[0], "Expected existing finalizers to be unchanged")
+}
+
+func TestUpdateImageStream(t *testing.T) {
+	scheme := getScheme(t)
+	assert.Nil(t, imagev1.AddToScheme(scheme), "Expect no errors building scheme")
+	client := fake.NewFakeClientWithScheme(scheme)
+	generation := int64(3)
+	existingStream := imagev1.ImageStream{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "stream1",
+			Namespace: "namespace",
+		},
+		Spec: imagev1.ImageStreamSpec{
+			DockerImageRepository: "registry/namespace/stream1",
+			Tags: []imagev1.TagReference{
+				{Name: "latest", From: &corev1.ObjectReference{Kind: "DockerImage", Name: "quay.io/namespace/image:1.0"}, Generation: &generation},
+			},
+		},
+		Status: imagev1.ImageStreamStatus{
+			DockerImageRepository: "registry/namespace/stream1",
+			Tags:                  []imagev1.NamedTagEventList{{Tag: "latest", Items: []imagev1.TagEvent{{Image: "sha256:1234", Generation: generation}}}},
+		},
+	}
+	assert.Nil(t, client.Create(context.TODO(), &existingStream), "Expect no errors mock creating object")
+
+	requestedStream := imagev1.ImageStream{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "stream1",
+			Namespace: "namespace",
+		},
+		Spec: imagev1.ImageStreamSpec{
+			Tags: []imagev1.TagReference{
+				{Name: "latest", From: &corev1.ObjectReference{Kind: "DockerImage", Name: "quay.io/namespace/image:2.0"}},
+			},
+		},
+	}
+	updated, err := New(client).UpdateResources([]resource.KubernetesResource{&existingStream}, []resource.KubernetesResource{&requestedStream})
+	assert.Nil(t, err, "Expect no errors updating object")
+	assert.True(t, updated, "Object should be updated")
+
+	updatedStream := imagev1.ImageStream{}
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "stream1", Namespace: "namespace"}, &updatedStream)
+	assert.Nil(t, err, "Expect no errors loading existing object")
+	assert.Equal(t, "quay.io/namespace/image:2.0", updatedStream.Spec.Tags[0].From.Name, "Expected tag source to be updated")
+	assert.Equal(t, &generation, updatedStream.Spec.Tags[0].Generation, "Expected tag generation to be carried over")
+	assert.Equal(t, existingStream.Spec.DockerImageRepository, updatedStream.Spec.DockerImageRepository, "Expected repository to be carried over")
+	assert.Equal(t, existingStream.Status, updatedStream.Status, "Expected tag history to be preserved")
+}
+
+func TestUpdateCustomResourceDefinition(t *testing.T) {
+	scheme := getScheme(t)
+	assert.Nil(t, apiextensionsv1beta1.AddToScheme(scheme), "Expect no errors building scheme")
+	client := fake.NewFakeClientWithScheme(scheme)
+	existingCRD := apiextensionsv1beta1.CustomResourceDefinition{
+		ObjectMeta: v1.ObjectMeta{Name: "myapps.example.com"},
+		Spec: apiextensionsv1beta1.CustomResourceDefinitionSpec{
+			Group:   "example.com",
+			Version: "v1",
+			Names:   apiextensionsv1beta1.CustomResourceDefinitionNames{Kind: "MyApp", Plural: "myapps"},
+		},
+		Status: apiextensionsv1beta1.CustomResourceDefinitionStatus{StoredVersions: []string{"v1"}},
+	}
+	assert.Nil(t, client.Create(context.TODO(), &existingCRD), "Expect no errors mock creating object")
+
+	requestedCRD := existingCRD.DeepCopy()
+	requestedCRD.ResourceVersion = ""
+	requestedCRD.Status = apiextensionsv1beta1.CustomResourceDefinitionStatus{}
+	requestedCRD.Spec.Validation = &apiextensionsv1beta1.CustomResourceValidation{
+		OpenAPIV3Schema: &apiextensionsv1beta1.JSONSchemaProps{Type: "object"},
+	}
+	updated, err := New(client).UpdateResources([]resource.KubernetesResource{&existingCRD}, []resource.KubernetesResource{requestedCRD})
+	assert.Nil(t, err, "Expect no errors updating object")
+	assert.True(t, updated, "Object should be updated")
+	assert.Equal(t, existingCRD.ResourceVersion, requestedCRD.ResourceVersion, "Expected resource version to be carried over")
+
+	updatedCRD := apiextensionsv1beta1.CustomResourceDefinition{}
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "myapps.example.com"}, &updatedCRD)
+	assert.Nil(t, err, "Expect no errors loading existing object")
+	assert.NotNil(t, updatedCRD.Spec.Validation, "Expected schema to be updated")
+	assert.Equal(t, []string{"v1"}, updatedCRD.Status.StoredVersions, "Expected status to be carried over")
+}
+
 func getScheme(t *testing.T) *runtime.Scheme {
 	scheme := runtime.NewScheme()
 	err := corev1.SchemeBuilder.AddToScheme(scheme)
 	assert.Nil(t, err, "Expect no errors building scheme")
 	return scheme
 }
+
+func TestPatchUpdateDeployment(t *testing.T) {
+	scheme := getScheme(t)
+	assert.Nil(t, appsv1.SchemeBuilder.AddToScheme(scheme), "Expect no errors building scheme")
+	client := fake.NewFakeClientWithScheme(scheme)
+	existingDeployment := appsv1.Deployment{
+		ObjectMeta: v1.ObjectMeta{
+			Name:              "deployment1",
+			Namespace:         "namespace",
+			Annotations:       map[string]string{"injected": "true"},
+			CreationTimestamp: v1.NewTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+			UID:               "deployment-uid",
+		},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "app", Image: "image:1.0"},
+						{Name: "sidecar", Image: "sidecar:1.0"},
+					},
+				},
+			},
+		},
+	}
+	existingDeployment.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
+	assert.Nil(t, client.Create(context.TODO(), &existingDeployment), "Expect no errors mock creating object")
+
+	writer := New(client).WithPatchUpdates()
+	requested := getPatchedDeployment("image:2.0", map[string]string{"app": "deployment1", "version": "2"}, corev1.EnvVar{Name: "DEBUG", Value: "true"})
+	updated, err := writer.UpdateResources([]resource.KubernetesResource{&existingDeployment}, []resource.KubernetesResource{requested})
+	assert.Nil(t, err, "Expect no errors updating object")
+	assert.True(t, updated, "Object should be updated")
+	assert.Len(t, requested.Spec.Template.Spec.Containers, 1, "Expected requested object to be left unchanged by the merge")
+	assert.Empty(t, requested.Annotations, "Expected requested object to be left unchanged by the merge")
+
+	deployment := &appsv1.Deployment{}
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "deployment1", Namespace: "namespace"}, deployment)
+	assert.Nil(t, err, "Expect no errors loading existing object")
+	assert.Equal(t, "true", deployment.Annotations["injected"], "Expected annotation set by another party to be preserved")
+	assert.Len(t, deployment.Spec.Template.Spec.Containers, 2, "Expected injected sidecar to be preserved")
+	assert.Equal(t, "image:2.0", deployment.Spec.Template.Spec.Containers[0].Image, "Expected requested image to be applied")
+	assert.Equal(t, existingDeployment.CreationTimestamp.Unix(), deployment.CreationTimestamp.Unix(), "Expected creation timestamp to be preserved")
+	assert.NotEmpty(t, deployment.Annotations[resource.LastAppliedAnnotation], "Expected requested fields to be recorded")
+
+	requested = getPatchedDeployment("image:2.0", map[string]string{"app": "deployment1", "version": "2"}, corev1.EnvVar{Name: "DEBUG", Value: "true"})
+	updated, err = writer.UpdateResources([]resource.KubernetesResource{deployment}, []resource.KubernetesResource{requested})
+	assert.Nil(t, err, "Expect no errors updating object")
+	assert.False(t, updated, "Object should not be updated when the patch is empty")
+
+	requested = getPatchedDeployment("image:2.0", map[string]string{"app": "deployment1"})
+	updated, err = writer.UpdateResources([]resource.KubernetesResource{deployment}, []resource.KubernetesResource{requested})
+	assert.Nil(t, err, "Expect no errors updating object")
+	assert.True(t, updated, "Object should be updated to remove fields no longer requested")
+	deployment = &appsv1.Deployment{}
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "deployment1", Namespace: "namespace"}, deployment)
+	assert.Nil(t, err, "Expect no errors loading existing object")
+	assert.Equal(t, map[string]string{"app": "deployment1"}, deployment.Labels, "Expected label no longer requested to be removed")
+	assert.Empty(t, deployment.Spec.Template.Spec.Containers[0].Env, "Expected environment variable no longer requested to be removed")
+	assert.Len(t, deployment.Spec.Template.Spec.Containers, 2, "Expected injected sidecar to be preserved")
+	assert.Equal(t, "true", deployment.Annotations["injected"], "Expected annotation set by another party to be preserved")
+
+	requested = getPatchedDeployment("image:2.0", map[string]string{"app": "deployment1"})
+	updated, err = writer.UpdateResources([]resource.KubernetesResource{deployment}, []resource.KubernetesResource{requested})
+	assert.Nil(t, err, "Expect no errors updating object")
+	assert.False(t, updated, "Object should not be updated once fields have been removed")
+}
+
+func getPatchedDeployment(image string, labels map[string]string, env ...corev1.EnvVar) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "deployment1",
+			Namespace: "namespace",
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "app", Image: image, Env: env},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestPatchUpdateAutoscaledDeployment(t *testing.T) {
+	scheme := getScheme(t)
+	assert.Nil(t, appsv1.SchemeBuilder.AddToScheme(scheme), "Expect no errors building scheme")
+	client := fake.NewFakeClientWithScheme(scheme)
+	scaledReplicas := int32(5)
+	existingDeployment := getPatchedDeployment("image:1.0", nil)
+	existingDeployment.Spec.Replicas = &scaledReplicas
+	existingDeployment.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
+	assert.Nil(t, client.Create(context.TODO(), existingDeployment), "Expect no errors mock creating object")
+	hpa := &autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: v1.ObjectMeta{Name: "autoscaler", Namespace: "namespace"},
+		Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "deployment1"},
+			MaxReplicas:    10,
+		},
+	}
+
+	deploymentType := reflect.TypeOf(appsv1.Deployment{})
+	hpaType := reflect.TypeOf(autoscalingv1.HorizontalPodAutoscaler{})
+	deployed := map[reflect.Type][]resource.KubernetesResource{deploymentType: {existingDeployment}, hpaType: {hpa.DeepCopy()}}
+	comparator := compare.NewMapComparator()
+	comparator.IgnoreAutoscaledReplicas = true
+	requested := map[reflect.Type][]resource.KubernetesResource{deploymentType: {getPatchedDeployment("image:1.0", nil)}, hpaType: {hpa}}
+	delta := comparator.Compare(deployed, requested)[deploymentType]
+	assert.False(t, delta.HasChanges(), "Expected replicas left to the autoscaler not to be reported")
+
+	requested[deploymentType] = []resource.KubernetesResource{getPatchedDeployment("image:2.0", nil)}
+	delta = comparator.Compare(deployed, requested)[deploymentType]
+	assert.Len(t, delta.Updated, 1, "Expected image change to be reported")
+	assert.Len(t, delta.Diffs[delta.Updated[0]], 1, "Expected only the image change to be reported")
+	updated, err := New(client).WithPatchUpdates().UpdateResources(deployed[deploymentType], delta.Updated)
+	assert.Nil(t, err, "Expect no errors updating object")
+	assert.True(t, updated, "Object should be updated")
+
+	deployment := &appsv1.Deployment{}
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "deployment1", Namespace: "namespace"}, deployment)
+	assert.Nil(t, err, "Expect no errors loading existing object")
+	assert.Equal(t, "image:2.0", deployment.Spec.Template.Spec.Containers[0].Image, "Expected requested image to be applied")
+	assert.Equal(t, scaledReplicas, *deployment.Spec.Replicas, "Expected replica count set by the autoscaler to be preserved")
+}
+
+func TestPatchUpdateRoute(t *testing.T) {
+	scheme := getScheme(t)
+	assert.Nil(t, routev1.AddToScheme(scheme), "Expect no errors building scheme")
+	client := fake.NewFakeClientWithScheme(scheme)
+	existingRoute := routev1.Route{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        "route1",
+			Namespace:   "namespace",
+			Annotations: map[string]string{"openshift.io/host.generated": "true"},
+		},
+		Spec: routev1.RouteSpec{
+			Host: "route1-namespace.example.com",
+			To:   routev1.RouteTargetReference{Kind: "Service", Name: "service1"},
+		},
+	}
+	existingRoute.SetGroupVersionKind(routev1.SchemeGroupVersion.WithKind("Route"))
+	assert.Nil(t, client.Create(context.TODO(), &existingRoute), "Expect no errors mock creating object")
+
+	requestedRoute := routev1.Route{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "route1",
+			Namespace: "namespace",
+		},
+		Spec: routev1.RouteSpec{
+			To: routev1.RouteTargetReference{Kind: "Service", Name: "service2"},
+		},
+	}
+	updated, err := New(client).WithPatchUpdates().UpdateResources([]resource.KubernetesResource{&existingRoute}, []resource.KubernetesResource{&requestedRoute})
+	assert.Nil(t, err, "Expect no errors updating object")
+	assert.True(t, updated, "Object should be updated")
+
+	route := routev1.Route{}
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "route1", Namespace: "namespace"}, &route)
+	assert.Nil(t, err, "Expect no errors loading existing object")
+	assert.Equal(t, "route1-namespace.example.com", route.Spec.Host, "Expected generated host to be preserved")
+	assert.Equal(t, "true", route.Annotations["openshift.io/host.generated"], "Expected generated annotation to be preserved")
+	assert.Equal(t, "service2", route.Spec.To.Name, "Expected requested target to be applied")
+
+	requestedRoute = routev1.Route{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "route1",
+			Namespace: "namespace",
+		},
+		Spec: routev1.RouteSpec{
+			Host: "custom.example.com",
+			To:   routev1.RouteTargetReference{Kind: "Service", Name: "service2"},
+		},
+	}
+	updated, err = New(client).WithPatchUpdates().UpdateResources([]resource.KubernetesResource{&route}, []resource.KubernetesResource{&requestedRoute})
+	assert.Nil(t, err, "Expect no errors updating object")
+	assert.True(t, updated, "Object should be updated")
+	route = routev1.Route{}
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "route1", Namespace: "namespace"}, &route)
+	assert.Nil(t, err, "Expect no errors loading existing object")
+	assert.Equal(t, "custom.example.com", route.Spec.Host, "Expected requested host to be applied")
+
+	//An empty host replaces the previously requested one, since it cannot have been set by another party
+	clearedRoute := routev1.Route{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "route1",
+			Namespace: "namespace",
+		},
+		Spec: routev1.RouteSpec{
+			To: routev1.RouteTargetReference{Kind: "Service", Name: "service2"},
+		},
+	}
+	updated, err = New(client).WithPatchUpdates().UpdateResources([]resource.KubernetesResource{&route}, []resource.KubernetesResource{&clearedRoute})
+	assert.Nil(t, err, "Expect no errors updating object")
+	assert.True(t, updated, "Object should be updated")
+	route = routev1.Route{}
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "route1", Namespace: "namespace"}, &route)
+	assert.Nil(t, err, "Expect no errors loading existing object")
+	assert.Equal(t, "", route.Spec.Host, "Expected empty host to be applied")
+}
+
+func TestPatchUpdateUnstructuredEmptyString(t *testing.T) {
+	scheme := getScheme(t)
+	assert.Nil(t, routev1.AddToScheme(scheme), "Expect no errors building scheme")
+	client := fake.NewFakeClientWithScheme(scheme)
+	existingRoute := routev1.Route{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "route1",
+			Namespace: "namespace",
+		},
+		Spec: routev1.RouteSpec{
+			Host: "route1-namespace.example.com",
+			To:   routev1.RouteTargetReference{Kind: "Service", Name: "service1"},
+		},
+	}
+	existingRoute.SetGroupVersionKind(routev1.SchemeGroupVersion.WithKind("Route"))
+	assert.Nil(t, client.Create(context.TODO(), &existingRoute), "Expect no errors mock creating object")
+	deployed := &unstructured.Unstructured{}
+	deployed.SetGroupVersionKind(routev1.SchemeGroupVersion.WithKind("Route"))
+	err := client.Get(context.TODO(), types.NamespacedName{Name: "route1", Namespace: "namespace"}, deployed)
+	assert.Nil(t, err, "Expect no errors loading existing object")
+
+	//Unlike typed objects, an unstructured object only holds the fields that are set, so an empty string is explicit
+	requested := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "route.openshift.io/v1",
+		"kind":       "Route",
+		"metadata":   map[string]interface{}{"name": "route1", "namespace": "namespace"},
+		"spec": map[string]interface{}{
+			"host": "",
+			"to":   map[string]interface{}{"kind": "Service", "name": "service1"},
+		},
+	}}
+	updated, err := New(client).WithPatchUpdates().UpdateResources([]resource.KubernetesResource{deployed}, []resource.KubernetesResource{requested})
+	assert.Nil(t, err, "Expect no errors updating object")
+	assert.True(t, updated, "Object should be updated")
+
+	route := routev1.Route{}
+	err = client.Get(context.TODO(), types.NamespacedName{Name: "route1", Namespace: "namespace"}, &route)
+	assert.Nil(t, err, "Expect no errors loading existing object")
+	assert.Equal(t, "", route.Spec.Host, "Expected explicit empty host to be applied")
+}
+
+type conflictingClient struct {
+	clientv1.Client
+	conflicts int
+}
+
+func (this *conflictingClient) Update(ctx context.Context, obj runtime.Object) error {
+	if this.conflicts > 0 {
+		this.conflicts--
+		return errors.NewConflict(schema.GroupResource{Resource: "services"}, "service1", newerror.New("object has been modified"))
+	}
+	return this.Client.Update(ctx, obj)
+}
+
+func TestUpdateConflictRetry(t *testing.T) {
+	scheme := getScheme(t)
+	client := fake.NewFakeClientWithScheme(scheme)
+	existingService := corev1.Service{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "service1",
+			Namespace: "namespace",
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "1.2.3.4",
+		},
+	}
+	existingService.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Service"))
+	assert.Nil(t, client.Create(context.TODO(), &existingService), "Expect no errors mock creating object")
+	backoff := wait.Backoff{Steps: 3, Duration: time.Millisecond, Factor: 1.0}
+
+	requestedService := corev1.Service{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "service1",
+			Namespace: "namespace",
+		},
+		Spec: corev1.ServiceSpec{
+			SessionAffinity: corev1.ServiceAffinityClientIP,
+		},
+	}
+	writer := New(&conflictingClient{Client: client, conflicts: 2}).WithConflictRetry(client, backoff)
+	updated, err := writer.UpdateResources([]resource.KubernetesResource{&existingService}, []resource.KubernetesResource{&requestedService})
+	assert.Nil(t, err, "Expect update to succeed after retrying conflicts")
+	assert.True(t, updated, "Object should be updated")
+	assert.Equal(t, "1.2.3.4", requestedService.Spec.ClusterIP, "Expected update hooks to be triggered on retry")
+
+	failingService := requestedService.DeepCopy()
+	writer = New(&conflictingClient{Client: client, conflicts: 10}).WithConflictRetry(client, backoff)
+	updated, err = writer.UpdateResources([]resource.KubernetesResource{&existingService}, []resource.KubernetesResource{failingService})
+	assert.False(t, updated, "Object should not be updated")
+	conflictErr, ok := err.(*ConflictError)
+	assert.True(t, ok, "Expected a conflict error once retries are exhausted")
+	assert.Equal(t, []resource.KubernetesResource{failingService}, conflictErr.Resources)
+
+	writer = New(&conflictingClient{Client: client, conflicts: 10}).WithConflictRetry(client, backoff)
+	report, err := writer.UpdateResourcesWithReport([]resource.KubernetesResource{&existingService}, []resource.KubernetesResource{failingService})
+	conflictErr, ok = err.(*ConflictError)
+	assert.True(t, ok, "Expected the same conflict error from the report variant")
+	assert.Equal(t, []resource.KubernetesResource{failingService}, conflictErr.Resources)
+	assert.Len(t, report.Failed(), 1, "Expected the conflicting resource to be reported as failed")
+	_, ok = report.Failed()[0].Error.(*ConflictError)
+	assert.True(t, ok, "Expected the result to hold a conflict error")
+}
+
+type contextKey string
+
+type contextRecorder struct {
+	clientv1.Client
+	contexts []context.Context
+}
+
+func (this *contextRecorder) Create(ctx context.Context, obj runtime.Object) error {
+	this.contexts = append(this.contexts, ctx)
+	return this.Client.Create(ctx, obj)
+}
+
+func (this *contextRecorder) Update(ctx context.Context, obj runtime.Object) error {
+	this.contexts = append(this.contexts, ctx)
+	return this.Client.Update(ctx, obj)
+}
+
+func (this *contextRecorder) Delete(ctx context.Context, obj runtime.Object, opts ...clientv1.DeleteOptionFunc) error {
+	this.contexts = append(this.contexts, ctx)
+	return this.Client.Delete(ctx, obj, opts...)
+}
+
+type contextHooks struct {
+	contexts []context.Context
+}
+
+func (this *contextHooks) Trigger(ctx context.Context, existing resource.KubernetesResource, requested resource.KubernetesResource) error {
+	this.contexts = append(this.contexts, ctx)
+	requested.SetResourceVersion(existing.GetResourceVersion())
+	return nil
+}
+
+func TestWriterContext(t *testing.T) {
+	scheme := getScheme(t)
+	client := &contextRecorder{Client: fake.NewFakeClientWithScheme(scheme)}
+	hooks := &contextHooks{}
+	ctx := context.WithValue(context.TODO(), contextKey("reconcile"), "request1")
+	writer := New(client).WithContext(ctx).WithCustomUpdateHooks(hooks)
+	service := &corev1.Service{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "service1",
+			Namespace: "namespace",
+		},
+	}
+	_, err := writer.AddResources([]resource.KubernetesResource{service})
+	assert.Nil(t, err, "Expect no errors adding object")
+	_, err = writer.UpdateResources([]resource.KubernetesResource{service}, []resource.KubernetesResource{service.DeepCopy()})
+	assert.Nil(t, err, "Expect no errors updating object")
+	_, err = writer.RemoveResources([]resource.KubernetesResource{service})
+	assert.Nil(t, err, "Expect no errors removing object")
+	assert.Equal(t, []context.Context{ctx, ctx, ctx}, client.contexts, "Expected context to be passed to create, update and delete calls")
+	assert.Equal(t, []context.Context{ctx}, hooks.contexts, "Expected context to be passed to update hooks")
+}
+
+func TestConflictRetryCancelled(t *testing.T) {
+	scheme := getScheme(t)
+	client := fake.NewFakeClientWithScheme(scheme)
+	existingService := &corev1.Service{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "service1",
+			Namespace: "namespace",
+		},
+	}
+	assert.Nil(t, client.Create(context.TODO(), existingService), "Expect no errors mock creating object")
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	conflicting := &conflictingClient{Client: client, conflicts: 10}
+	backoff := wait.Backoff{Steps: 5, Duration: time.Millisecond, Factor: 1.0}
+	writer := New(conflicting).WithContext(ctx).WithConflictRetry(client, backoff)
+	updated, err := writer.UpdateResources([]resource.KubernetesResource{existingService}, []resource.KubernetesResource{existingService.DeepCopy()})
+	assert.False(t, updated, "Object should not be updated")
+	assert.Equal(t, context.Canceled, err, "Expected retries to stop once the context is cancelled")
+	assert.Equal(t, 9, conflicting.conflicts, "Expected no update attempts after the context is cancelled")
+}
+
+func TestAddResourcesReport(t *testing.T) {
+	scheme := getScheme(t)
+	client := fake.NewFakeClientWithScheme(scheme)
+	services := make([]corev1.Service, 3)
+	for index := range services {
+		services[index] = corev1.Service{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      fmt.Sprintf("service%d", index+1),
+				Namespace: "namespace",
+			},
+		}
+	}
+	assert.Nil(t, client.Create(context.TODO(), services[1].DeepCopy()), "Expect no errors mock creating object")
+
+	resources := []resource.KubernetesResource{services[0].DeepCopy(), services[1].DeepCopy(), services[2].DeepCopy()}
+	report, err := New(client).AddResourcesWithReport(resources)
+	assert.True(t, errors.IsAlreadyExists(err), "Expected the original error to be returned for a single failure")
+	assert.True(t, report.HasChanges(), "Expected first service to be added")
+	assert.Equal(t, []ResultStatus{Succeeded, Failed, Skipped}, getStatuses(report))
+
+	resources = []resource.KubernetesResource{services[0].DeepCopy(), services[1].DeepCopy(), services[2].DeepCopy()}
+	report, err = New(client).WithContinueOnError().AddResourcesWithReport(resources)
+	assert.NotNil(t, err, "Expected an aggregate error")
+	assert.Equal(t, []ResultStatus{Failed, Failed, Succeeded}, getStatuses(report))
+	assert.Len(t, report.Failed(), 2, "Expected two failed results")
+	assert.Equal(t, resources[0], report.Failed()[0].Resource)
+
+	removed, err := New(client).WithContinueOnError().RemoveResources([]resource.KubernetesResource{&corev1.Service{ObjectMeta: v1.ObjectMeta{Name: "missing", Namespace: "namespace"}}, resources[2]})
+	assert.True(t, errors.IsNotFound(err), "Expected the original error to be returned for a single failure")
+	assert.True(t, removed, "Expected the existing service to be removed despite the earlier failure")
+}
+
+func getStatuses(report Report) []ResultStatus {
+	var statuses []ResultStatus
+	for _, result := range report.Results {
+		statuses = append(statuses, result.Status)
+	}
+	return statuses
+}
+
+func TestDryRunPlan(t *testing.T) {
+	scheme := getScheme(t)
+	client := fake.NewFakeClientWithScheme(scheme)
+	existingService := corev1.Service{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "service1",
+			Namespace: "namespace",
+		},
+	}
+	assert.Nil(t, client.Create(context.TODO(), &existingService), "Expect no errors mock creating object")
+	updatedService := existingService.DeepCopy()
+	updatedService.Spec.SessionAffinity = corev1.ServiceAffinityClientIP
+	addedService := corev1.Service{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "service2",
+			Namespace: "namespace",
+		},
+	}
+
+	ownerRef := v1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "owner", UID: "owner-uid"}
+	writer := New(client).WithOwnerReferences(ownerRef).WithDryRun()
+	added, err := writer.AddResources([]resource.KubernetesResource{&addedService})
+	assert.Nil(t, err, "Expect no errors in dry run")
+	assert.True(t, added, "Expected service to be planned for creation")
+	updated, err := writer.UpdateResources([]resource.KubernetesResource{&existingService}, []resource.KubernetesResource{updatedService})
+	assert.Nil(t, err, "Expect no errors in dry run")
+	assert.True(t, updated, "Expected service to be planned for update")
+	removed, err := writer.RemoveResources([]resource.KubernetesResource{&existingService})
+	assert.Nil(t, err, "Expect no errors in dry run")
+	assert.True(t, removed, "Expected service to be planned for removal")
+
+	plan := writer.Plan()
+	assert.Len(t, plan, 3, "Expected three planned operations")
+	assert.Equal(t, []Action{Create, Update, Delete}, []Action{plan[0].Action, plan[1].Action, plan[2].Action})
+	assert.Equal(t, "Service", plan[0].Kind)
+	assert.Equal(t, "service2", plan[0].Name)
+	assert.Len(t, plan[1].Diffs, 1, "Expected the update to carry its diff")
+	assert.Equal(t, "spec.sessionAffinity", plan[1].Diffs[0].Path)
+	assert.Empty(t, addedService.OwnerReferences, "Expected the provided object to be left unchanged in dry run")
+	assert.Empty(t, updatedService.OwnerReferences, "Expected the provided object to be left unchanged in dry run")
+	assert.Equal(t, []v1.OwnerReference{ownerRef}, plan[0].Resource.GetOwnerReferences(), "Expected the planned object to carry ownership")
+	assert.Equal(t, []v1.OwnerReference{ownerRef}, plan[1].Resource.GetOwnerReferences(), "Expected the planned object to carry ownership")
+
+	services := &corev1.ServiceList{}
+	assert.Nil(t, client.List(context.TODO(), &clientv1.ListOptions{Namespace: "namespace"}, services), "Expect no errors listing objects")
+	assert.Len(t, services.Items, 1, "Expected no changes to be made in dry run")
+	assert.Equal(t, corev1.ServiceAffinity(""), services.Items[0].Spec.SessionAffinity, "Expected no changes to be made in dry run")
+
+	writer.ResetPlan()
+	assert.Empty(t, writer.Plan(), "Expected reset to discard planned operations")
+	//Diffs of planned updates come from the configured comparator
+	comparator := compare.SimpleComparator()
+	comparator.SetComparator(reflect.TypeOf(corev1.Service{}), func(deployed resource.KubernetesResource, requested resource.KubernetesResource) bool {
+		return deployed.GetName() == requested.GetName()
+	})
+	_, err = writer.WithComparator(comparator).UpdateResources([]resource.KubernetesResource{&existingService}, []resource.KubernetesResource{updatedService.DeepCopy()})
+	assert.Nil(t, err, "Expect no errors in dry run")
+	assert.Len(t, writer.Plan(), 1, "Expected only the operation planned after reset")
+	assert.Empty(t, writer.Plan()[0].Diffs, "Expected the configured comparator to find no differences")
+}